@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math/rand"
 
+	"xicserver/bribe/engine"
 	"xicserver/models"
 
 	"github.com/gorilla/websocket"
@@ -18,14 +19,6 @@ func sendErrorMessage(client *models.Client, errorMessage string) {
 	client.Conn.WriteMessage(websocket.TextMessage, errorJSON) // Ignoring error for simplicity
 }
 
-// 現在のプレイヤーのシンボルを取得するヘルパー関数
-func getCurrentPlayerSymbol(client *models.Client, game *models.Game) string {
-	if game.Players[0].ID == client.UserID {
-		return game.Players[0].Symbol
-	}
-	return game.Players[1].Symbol
-}
-
 // クライアントごとにメッセージ読み取りするゴルーチン
 func HandleClient(client *models.Client, clients map[*models.Client]bool, games map[uint]*models.Game, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
 	defer func() {
@@ -57,25 +50,34 @@ func HandleClient(client *models.Client, clients map[*models.Client]bool, games
 		}
 
 		// メッセージタイプに基づいて適切なアクションを実行
-		switch msg["type"].(string) {
+		msgType, _ := msg["type"].(string)
+		switch msgType {
 		case "action":
-			// case "markCell", "bribe", "accuse", "retry":
 			// ここでさらにアクションタイプに応じて処理を分岐
-			actionType := msg["actionType"].(string)
+			actionType, _ := msg["actionType"].(string)
 			switch actionType {
-			case "markCell":
-				handleMarkCell(client, msg, game, randGen, db, logger)
-			case "bribe":
-				handleBribe(game, client, logger)
-			case "accuse":
-				handleAccuse(game, client, logger)
-			case "retry":
-				handleRetry(game, client, clients, msg, logger, db)
+			case engine.ActionMarkCell:
+				handleMarkCell(client, msg, game, clients, randGen, db, logger)
+			case engine.ActionBribe, engine.ActionAccuse, engine.ActionUndoRequest:
+				action := engine.Action{Type: actionType, PlayerID: client.UserID}
+				applyAction(client, game, action, clients, randGen, db, logger)
+			case engine.ActionRetry:
+				handleRetry(client, msg, game, clients, randGen, db, logger)
+			case engine.ActionResign, engine.ActionOfferDraw:
+				handleResignOrOfferDraw(client, actionType, msg, game, clients, randGen, db, logger)
+			case engine.ActionRespondDraw:
+				handleRespondDraw(client, msg, game, clients, randGen, db, logger)
+			case engine.ActionUndoResponse:
+				handleUndoResponse(client, msg, game, clients, randGen, db, logger)
+			case engine.ActionPowerUp:
+				handlePowerUp(client, msg, game, clients, randGen, db, logger)
+			case engine.ActionCollapse:
+				handleCollapse(client, msg, game, clients, randGen, db, logger)
 			default:
 				logger.Info("Unknown action type", zap.String("actionType", actionType))
 			}
 		case "chatMessage":
-			handleChatMessage(client, msg, clients, logger)
+			handleChatMessage(client, msg, game, clients, logger)
 		default:
 			logger.Info("Received unknown message type", zap.Any("message", msg))
 		}
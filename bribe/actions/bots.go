@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"xicserver/bribe/bot"
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ボットが行動するまでの待ち時間。人間のプレイヤーが盤面の変化を確認できるように間を空ける
+const botThinkTime = 700 * time.Millisecond
+
+// ゲームに参加しているボットと、次の行動のタイマー
+type botSeats struct {
+	bots     []*bot.Bot
+	timer    *time.Timer
+	thinking sync.Mutex // ボットの探索はgame.Muの外で行うため、同じボットが同時に考えないように直列化する。game.Muより先に取得する
+}
+
+// キー: ゲームID。複数のゲームのゴルーチンから使用するため、botsMuで保護する
+var (
+	botsByGame = make(map[uint]*botSeats)
+	botsMu     sync.Mutex
+)
+
+// ScheduleBots はゲームにボットが参加している場合に、ボットの次の行動を予約します。
+// ボットはWebSocketを持たないため、クライアントと同じアクションをサーバー側から適用します。
+func ScheduleBots(game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	game.Mu.Lock()
+	defer game.Mu.Unlock()
+	scheduleBots(game, clients, randGen, db, logger)
+}
+
+// game.Muを取得した状態でボットの次の行動を予約し直すヘルパー関数
+func scheduleBots(game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	seats := botsFor(game, logger)
+	if seats == nil {
+		return
+	}
+	if seats.timer != nil {
+		seats.timer.Stop()
+		seats.timer = nil
+	}
+	if game.Status == engine.StatusFinished {
+		botsMu.Lock()
+		delete(botsByGame, game.ID)
+		botsMu.Unlock()
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(botThinkTime, func() {
+		// ボットの状態はアクションを適用した時だけ反映するため、考え始めてから反映するまでボットを直列化する
+		seats.thinking.Lock()
+		defer seats.thinking.Unlock()
+
+		// 探索には時間がかかるため、game.Muを取得するのは状態の複製とアクションの適用の間だけにする
+		game.Mu.Lock()
+		snapshot := engine.Snapshot(game)
+		game.Mu.Unlock()
+
+		b, action, state, ok := nextBotAction(seats, snapshot)
+		if !ok {
+			return
+		}
+
+		game.Mu.Lock()
+		defer game.Mu.Unlock()
+		// 考えている間に他のアクションが適用された場合は、ボットの状態を戻したまま予約し直されたタイマーに任せる
+		if seats.timer != timer {
+			logger.Info("Bot action discarded because the game has changed", zap.Uint("RoomID", game.ID), zap.Uint("PlayerID", action.PlayerID))
+			return
+		}
+		if applyActionLocked(nil, game, action, clients, randGen, db, logger) {
+			b.Commit(state)
+		}
+	})
+	seats.timer = timer
+}
+
+// ゲームの状態の複製から、ボットが次に行うアクションを1つ、行うボットと判断した後の状態とともに返すヘルパー関数。
+// 1回に1つのアクションだけを適用し、続きは適用後に予約し直す
+func nextBotAction(seats *botSeats, snapshot *models.Game) (*bot.Bot, engine.Action, bot.State, bool) {
+	for _, b := range seats.bots {
+		if action, state, ok := b.NextAction(snapshot); ok {
+			return b, action, state, true
+		}
+	}
+	return nil, engine.Action{}, bot.State{}, false
+}
+
+// ゲームに参加しているボットを返すヘルパー関数。初めて呼ばれた時にボットを作成し、ボットがいない場合はnil
+func botsFor(game *models.Game, logger *zap.Logger) *botSeats {
+	botsMu.Lock()
+	defer botsMu.Unlock()
+
+	if seats, ok := botsByGame[game.ID]; ok {
+		return seats
+	}
+	seats := &botSeats{}
+	for _, player := range game.Players {
+		if player != nil && player.Bot {
+			seats.bots = append(seats.bots, bot.New(player.ID, player.BotSeed, player.BotLevel))
+			logger.Info("Bot created", zap.Uint("RoomID", game.ID), zap.Uint("PlayerID", player.ID), zap.String("level", player.BotLevel), zap.Int64("seed", player.BotSeed))
+		}
+	}
+	if len(seats.bots) == 0 {
+		return nil
+	}
+	botsByGame[game.ID] = seats
+	return seats
+}
@@ -4,16 +4,49 @@ import (
 	"encoding/json"
 	"time"
 
+	"xicserver/bribe/engine"
 	"xicserver/models"
 
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
+// チャットのチャンネル。"channel"が省略された場合はルーム全体に送信する
+const (
+	ChannelRoom = "room" // ルーム内の全員
+	ChannelTeam = "team" // チーム戦で同じチームのプレイヤーのみ
+)
+
 // チャットメッセージを処理する関数
-func handleChatMessage(client *models.Client, msg map[string]interface{}, clients map[*models.Client]bool, logger *zap.Logger) {
+func handleChatMessage(client *models.Client, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, logger *zap.Logger) {
+	// チームの判定でゲームの状態を読むため、送信が終わるまでロックを取得する
+	game.Mu.Lock()
+	defer game.Mu.Unlock()
+
 	// ここではmsgからチャットメッセージを取り出す
-	chatMessage := msg["message"].(string)
+	chatMessage, ok := msg["message"].(string)
+	if !ok {
+		sendErrorMessage(client, "Invalid chat message")
+		return
+	}
+
+	channel, _ := msg["channel"].(string)
+	if channel == "" {
+		channel = ChannelRoom
+	}
+	team := engine.TeamOf(game, client.UserID)
+	switch channel {
+	case ChannelRoom:
+	case ChannelTeam:
+		// チームチャットはチーム戦に参加しているプレイヤーのみ使える
+		if team == -1 {
+			sendErrorMessage(client, "Team chat is not available")
+			return
+		}
+	default:
+		sendErrorMessage(client, "Invalid chat channel")
+		return
+	}
 
 	// 現在のタイムスタンプを取得
 	timestamp := time.Now().Format(time.RFC3339)
@@ -21,6 +54,7 @@ func handleChatMessage(client *models.Client, msg map[string]interface{}, client
 	logger.Info("Received chat message",
 		zap.String("message", chatMessage),
 		zap.Uint("from", client.UserID),
+		zap.String("channel", channel),
 		zap.String("timestamp", timestamp),
 	)
 
@@ -28,9 +62,14 @@ func handleChatMessage(client *models.Client, msg map[string]interface{}, client
 	for c := range clients {
 		// 同じゲームルーム内のクライアントにのみメッセージを送信するロジック
 		if c.RoomID == client.RoomID {
+			// チームチャットは同じチームのプレイヤーにのみ送信する
+			if channel == ChannelTeam && engine.TeamOf(game, c.UserID) != team {
+				continue
+			}
 			message := map[string]interface{}{
 				"type":      "chatMessage",
 				"message":   chatMessage,
+				"channel":   channel,
 				"from":      client.UserID, // 送信者の識別子
 				"timestamp": timestamp,     // メッセージのタイムスタンプ
 			}
@@ -0,0 +1,30 @@
+package actions
+
+import (
+	"math/rand"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 量子三目並べの測定を処理する関数。循環を作った手の2つのセルのうち、確定させるセル（"x"、"y"）を指定する
+func handleCollapse(client *models.Client, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	xFloat, okX := msg["x"].(float64)
+	yFloat, okY := msg["y"].(float64)
+	if !okX || !okY {
+		sendErrorMessage(client, "Invalid cell coordinates")
+		logger.Error("Invalid collapse request", zap.Any("message", msg))
+		return
+	}
+
+	action := engine.Action{
+		Type:     engine.ActionCollapse,
+		PlayerID: client.UserID,
+		X:        int(xFloat),
+		Y:        int(yFloat),
+	}
+	applyAction(client, game, action, clients, randGen, db, logger)
+}
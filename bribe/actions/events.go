@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"math/rand"
+	"time"
+
+	"xicserver/bribe/broadcast"
+	"xicserver/bribe/database"
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ルールエンジンにアクションを適用し、結果のイベントを送信・永続化するヘルパー関数
+func applyAction(client *models.Client, game *models.Game, action engine.Action, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	game.Mu.Lock()
+	defer game.Mu.Unlock()
+	applyActionLocked(client, game, action, clients, randGen, db, logger)
+}
+
+// game.Muを取得した状態でアクションを適用し、適用できたかどうかを返すヘルパー関数。clientがnilの場合はサーバー側から発生したアクション
+func applyActionLocked(client *models.Client, game *models.Game, action engine.Action, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) bool {
+	if action.At.IsZero() {
+		action.At = time.Now()
+	}
+
+	next, events, err := engine.Apply(game, action, randGen)
+	if err != nil {
+		if client != nil {
+			sendErrorMessage(client, err.Error())
+		}
+		logger.Error("Action rejected", zap.String("actionType", action.Type), zap.Uint("PlayerID", action.PlayerID), zap.Error(err))
+		return false
+	}
+
+	// gamesマップが保持するポインタはそのままに、中身を新しい状態に置き換える。
+	// エンジンは盤面を複製してから変更するため、置き換える前の状態は盤面の差分の送信に使える
+	previous := *game
+	*game = *next
+	logger.Info("Action applied", zap.String("actionType", action.Type), zap.Uint("PlayerID", action.PlayerID), zap.String("status", game.Status))
+
+	dispatchEvents(&previous, game, events, clients, db, logger)
+	scheduleTurnTimer(game, clients, randGen, db, logger)
+	scheduleBots(game, clients, randGen, db, logger)
+	return true
+}
+
+// エンジンが返したイベントに応じてメッセージ送信やデータベース更新を行う。previousはアクションを適用する前の状態
+func dispatchEvents(previous *models.Game, game *models.Game, events []engine.Event, clients map[*models.Client]bool, db *gorm.DB, logger *zap.Logger) {
+	for _, event := range events {
+		switch event.Type {
+		case engine.EventSystemMessage:
+			if event.To == 0 {
+				sendMessageBoth(game, event.Message, logger)
+			} else {
+				sendSystemMessage(game, event.To, event.Message, logger)
+			}
+		case engine.EventGameState:
+			broadcast.BroadcastGameStateChanges(previous, game, logger)
+			logger.Info("Game state broadcasted")
+		case engine.EventResults:
+			broadcast.BroadcastResults(game, logger)
+			logger.Info("Game results broadcasted")
+		case engine.EventGameFinished:
+			if err := database.FinishGameRoom(db, game.ID); err != nil {
+				logger.Error("Failed to finalize game room updates", zap.Error(err))
+			}
+		case engine.EventWaitingForMoves:
+			broadcast.BroadcastWaitingForMoves(game, logger)
+		case engine.EventRetryRequested:
+			sendRetryRequestNotification(event.To, clients, logger)
+		default:
+			logger.Info("Unknown event type", zap.String("eventType", event.Type))
+		}
+	}
+}
+
+func sendMessageBoth(game *models.Game, message string, logger *zap.Logger) {
+	for _, player := range game.Players {
+		if player != nil && player.Conn != nil {
+			chatMessage := map[string]interface{}{
+				"type":    "chatMessage",
+				"message": message,
+				"from":    0, // 0 indicates system message
+			}
+			err := player.Conn.WriteJSON(chatMessage)
+			if err != nil {
+				logger.Error("Failed to send message", zap.Error(err))
+			} else {
+				logger.Info("Message sent to both players", zap.String("message", message), zap.Uint("PlayerID", player.ID))
+			}
+		}
+	}
+}
+
+func sendSystemMessage(game *models.Game, playerID uint, message string, logger *zap.Logger) {
+	for _, player := range game.Players {
+		if player != nil && player.ID == playerID && player.Conn != nil {
+			chatMessage := map[string]interface{}{
+				"type":    "chatMessage",
+				"message": message,
+				"from":    0, // 0 indicates system message
+			}
+			err := player.Conn.WriteJSON(chatMessage)
+			if err != nil {
+				logger.Error("Failed to send system message", zap.Error(err))
+			} else {
+				logger.Info("System message sent", zap.String("message", message), zap.Uint("PlayerID", playerID))
+			}
+		}
+	}
+}
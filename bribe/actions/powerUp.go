@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"math/rand"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// パワーアップの使用を処理する関数。入れ替えの場合は2つ目のセル（"x2"、"y2"）も指定する
+func handlePowerUp(client *models.Client, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	powerUp, okKind := msg["powerUp"].(string)
+	xFloat, okX := msg["x"].(float64)
+	yFloat, okY := msg["y"].(float64)
+	if !okKind || !okX || !okY {
+		sendErrorMessage(client, "Invalid power-up request")
+		logger.Error("Invalid power-up request", zap.Any("message", msg))
+		return
+	}
+
+	action := engine.Action{
+		Type:     engine.ActionPowerUp,
+		PlayerID: client.UserID,
+		PowerUp:  powerUp,
+		X:        int(xFloat),
+		Y:        int(yFloat),
+	}
+	if powerUp == models.PowerUpSwap {
+		x2Float, okX2 := msg["x2"].(float64)
+		y2Float, okY2 := msg["y2"].(float64)
+		if !okX2 || !okY2 {
+			sendErrorMessage(client, "Invalid power-up request")
+			logger.Error("Invalid swap target", zap.Any("message", msg))
+			return
+		}
+		action.X2, action.Y2 = int(x2Float), int(y2Float)
+	}
+	applyAction(client, game, action, clients, randGen, db, logger)
+}
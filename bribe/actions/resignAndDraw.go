@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"math/rand"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 投了と引き分けの提案を処理する関数。"scope"は省略可能で、"round"または"match"を指定する
+func handleResignOrOfferDraw(client *models.Client, actionType string, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	scope, _ := msg["scope"].(string)
+
+	action := engine.Action{
+		Type:     actionType,
+		PlayerID: client.UserID,
+		Scope:    scope,
+	}
+	applyAction(client, game, action, clients, randGen, db, logger)
+}
+
+// 引き分けの提案への返答を処理する関数
+func handleRespondDraw(client *models.Client, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	accept, ok := msg["accept"].(bool)
+	if !ok {
+		sendErrorMessage(client, "Invalid draw response")
+		logger.Error("Invalid draw response", zap.Any("message", msg))
+		return
+	}
+
+	action := engine.Action{
+		Type:     engine.ActionRespondDraw,
+		PlayerID: client.UserID,
+		Accept:   accept,
+	}
+	applyAction(client, game, action, clients, randGen, db, logger)
+}
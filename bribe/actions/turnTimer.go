@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"math/rand"
+	"time"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ScheduleTurnTimer はゲームの手番の期限に合わせてタイマーを設定し直します。
+// 期限が来るとtimeoutアクションをエンジンに適用し、ルールで決められたペナルティを与えます。
+func ScheduleTurnTimer(game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	game.Mu.Lock()
+	defer game.Mu.Unlock()
+	scheduleTurnTimer(game, clients, randGen, db, logger)
+}
+
+// game.Muを取得した状態でタイマーを設定し直すヘルパー関数
+func scheduleTurnTimer(game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	if game.TurnTimer != nil {
+		game.TurnTimer.Stop()
+		game.TurnTimer = nil
+	}
+	if game.TurnDeadline.IsZero() {
+		return
+	}
+
+	deadline := game.TurnDeadline
+	game.TurnTimer = time.AfterFunc(time.Until(deadline), func() {
+		game.Mu.Lock()
+		defer game.Mu.Unlock()
+
+		// 期限が更新されている場合は、古いタイマーなので何もしない
+		if !game.TurnDeadline.Equal(deadline) {
+			return
+		}
+		logger.Info("Turn timer expired", zap.Uint("RoomID", game.ID), zap.Uint("CurrentTurn", game.CurrentTurn))
+
+		action := engine.Action{Type: engine.ActionTimeout, PlayerID: game.CurrentTurn, At: time.Now()}
+		applyActionLocked(nil, game, action, clients, randGen, db, logger)
+	})
+}
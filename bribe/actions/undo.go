@@ -0,0 +1,28 @@
+package actions
+
+import (
+	"math/rand"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 待ったの要求への返答を処理する関数
+func handleUndoResponse(client *models.Client, msg map[string]interface{}, game *models.Game, clients map[*models.Client]bool, randGen *rand.Rand, db *gorm.DB, logger *zap.Logger) {
+	accept, ok := msg["accept"].(bool)
+	if !ok {
+		sendErrorMessage(client, "Invalid takeback response")
+		logger.Error("Invalid takeback response", zap.Any("message", msg))
+		return
+	}
+
+	action := engine.Action{
+		Type:     engine.ActionUndoResponse,
+		PlayerID: client.UserID,
+		Accept:   accept,
+	}
+	applyAction(client, game, action, clients, randGen, db, logger)
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"math/rand"
+	"slices"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+)
+
+// IDBase 以上のプレイヤーIDはボットに割り当てる。ユーザーIDと重ならないように大きな値から始める
+const IDBase uint = 1 << 31
+
+// NickName はボットのニックネーム
+const NickName = "Bot"
+
+// ボットの強さ
+const (
+	LevelEasy   = "easy"   // ランダムに手を選び、気まぐれに賄賂を贈る
+	LevelMedium = "medium" // 数手先まで読み、見えている賄賂に賄賂で対抗する
+	LevelHard   = "hard"   // 小さな盤面は読み切り、大きな盤面は評価関数で読む。賄賂と糾弾を使い分ける
+)
+
+// PlayerID は席の番号からボットのプレイヤーIDを返す
+func PlayerID(seat int) uint {
+	return IDBase + uint(seat)
+}
+
+// IsBot はプレイヤーIDがボットのものかどうかを返す
+func IsBot(playerID uint) bool {
+	return playerID >= IDBase
+}
+
+// IsValidLevel はボットの強さが定義されたものかどうかを返す
+func IsValidLevel(level string) bool {
+	return level == LevelEasy || level == LevelMedium || level == LevelHard
+}
+
+// Bot はサーバー側で席を埋めるプレイヤー。WebSocketの代わりに、次に行うアクションを呼び出し側に返す。
+// 乱数はボットの状態から判断ごとに作り、探索も局面の数で打ち切るため、同じシードで同じ局面を与えれば同じ判断を繰り返す
+type Bot struct {
+	ID      uint
+	Level   string
+	state   State
+	randGen *rand.Rand // 判断中だけ使う乱数。state.seedから作る
+}
+
+// State はボットが判断に使う状態。NextActionは新しい状態を返すだけで、Commitするまでボットには反映されない
+type State struct {
+	seed    int64  // 次の判断で使う乱数のシード
+	weighed turn   // 賄賂と糾弾を検討済みの手番
+	belief  belief // 審判の傾きについての推測
+}
+
+// ボットが賄賂や糾弾を検討した手番を表す
+type turn struct {
+	round      int
+	moveNumber int
+}
+
+// New はシードと強さを指定してボットを作成する。強さが定義されていない場合はLevelEasyとなる
+func New(id uint, seed int64, level string) *Bot {
+	if !IsValidLevel(level) {
+		level = LevelEasy
+	}
+	return &Bot{ID: id, Level: level, state: State{seed: seed, weighed: turn{round: -1}}}
+}
+
+// NextAction はゲームの現在の状態でボットが次に行うアクションと、判断した後のボットの状態を返す。
+// 行うアクションがない場合はfalse。ボットの状態は変更しないため、アクションを適用した場合だけCommitに渡す
+func (b *Bot) NextAction(game *models.Game) (engine.Action, State, bool) {
+	work := *b
+	work.state.belief.bribes = slices.Clone(b.state.belief.bribes)
+	work.randGen = rand.New(rand.NewSource(b.state.seed))
+	action, ok := work.nextAction(game)
+	work.state.seed = work.randGen.Int63()
+	return action, work.state, ok
+}
+
+// Commit はNextActionが返した状態をボットに反映する
+func (b *Bot) Commit(state State) {
+	b.state = state
+}
+
+// ボットの状態の複製で次のアクションを決めるヘルパー関数
+func (b *Bot) nextAction(game *models.Game) (engine.Action, bool) {
+	switch game.Status {
+	case engine.StatusRoundFinished:
+		// ボットは常に再戦を希望する
+		if _, answered := game.RetryRequests[b.ID]; !answered {
+			return engine.Action{Type: engine.ActionRetry, PlayerID: b.ID, WantRetry: true}, true
+		}
+		return engine.Action{}, false
+	case engine.StatusInProgress:
+	default:
+		return engine.Action{}, false
+	}
+	b.observe(game)
+
+	// 引き分けの提案は断り、待ったの要求は受け入れる
+	if offer := game.DrawOffer; offer != nil && offer.From != b.ID && !slices.Contains(offer.AcceptedBy, b.ID) {
+		return engine.Action{Type: engine.ActionRespondDraw, PlayerID: b.ID, Accept: false}, true
+	}
+	if request := game.UndoRequest; request != nil && request.From != b.ID {
+		return engine.Action{Type: engine.ActionUndoResponse, PlayerID: b.ID, Accept: true}, true
+	}
+
+	// 同時手番では、まだ提出していなければ手を提出する
+	if game.Rules.Simultaneous {
+		if !slices.Contains(engine.WaitingFor(game), b.ID) {
+			return engine.Action{}, false
+		}
+	} else if game.CurrentTurn != b.ID {
+		return engine.Action{}, false
+	}
+	if action, ok := b.weigh(game); ok {
+		return action, true
+	}
+	return b.chooseMove(game)
+}
+
+// 強さに応じて手を選ぶヘルパー関数。量子三目並べで測定待ちの場合は、測定するセルを選ぶ
+func (b *Bot) chooseMove(game *models.Game) (engine.Action, bool) {
+	if b.Level == LevelEasy {
+		// 探索と同じく、霧のルールではボットから見える盤面から候補を選ぶ
+		visible := *game
+		visible.Board = engine.VisibleBoard(game, b.ID)
+		candidates := rootActions(&visible, b.ID)
+		if len(candidates) == 0 {
+			return engine.Action{}, false
+		}
+		return candidates[b.randGen.Intn(len(candidates))], true
+	}
+	return newSearcher(game, b).bestAction()
+}
+
+// 局面でボットが選べる手を返すヘルパー関数
+func rootActions(game *models.Game, playerID uint) []engine.Action {
+	if game.Quantum != nil && game.Quantum.Collapse != nil {
+		var actions []engine.Action
+		for _, cell := range game.Quantum.Collapse.Cells {
+			actions = append(actions, engine.Action{Type: engine.ActionCollapse, PlayerID: playerID, X: cell[0], Y: cell[1]})
+		}
+		return actions
+	}
+	return engine.MoveCandidates(game, playerID)
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+)
+
+// テストの開始時刻
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// 3x3の三目並べのルール。審判が中立なら常に選択どおりに印を置く
+func testRules(bias string) models.GameRules {
+	return models.GameRules{
+		BoardWidth:   3,
+		BoardHeight:  3,
+		WinLength:    3,
+		MatchFormat:  models.MatchBestOf,
+		Rounds:       3,
+		TargetWins:   2,
+		Bias:         bias,
+		MarkAccuracy: 1,
+	}
+}
+
+// 2体のボットが参加し、1体目の手番で始まるゲームを作成するヘルパー関数
+func newTestGame(t *testing.T, rules models.GameRules) *models.Game {
+	t.Helper()
+	game := &models.Game{
+		ID:            1,
+		Players:       make([]*models.Player, engine.PlayerCount(rules)),
+		Status:        engine.StatusInProgress,
+		Round:         1,
+		Rules:         rules,
+		RefereeStatus: "normal_01",
+		BribeCounts:   make([]int, engine.PlayerCount(rules)),
+	}
+	engine.ResetBoard(game, rand.New(rand.NewSource(1)))
+	for seat := range game.Players {
+		game.Players[seat] = &models.Player{ID: PlayerID(seat), Symbol: engine.SeatSymbol(rules, seat), Bot: true}
+	}
+	engine.StartMatch(game, PlayerID(0), testStart)
+	return game
+}
+
+// ラウンドが終わるまでボットのアクションを適用し、適用したアクションを返すヘルパー関数。
+// discardがtrueの場合は、各アクションの前に判断を1回捨ててボットの状態が変わらないことを確かめる
+func playRound(t *testing.T, game *models.Game, bots []*Bot, randGen *rand.Rand, discard bool) []engine.Action {
+	t.Helper()
+	var actions []engine.Action
+	for step := 0; game.Status == engine.StatusInProgress; step++ {
+		if step >= 100 {
+			t.Fatalf("round did not finish after %d actions", step)
+		}
+		var (
+			b      *Bot
+			action engine.Action
+			state  State
+			ok     bool
+		)
+		for _, candidate := range bots {
+			if discard {
+				candidate.NextAction(game)
+			}
+			if action, state, ok = candidate.NextAction(game); ok {
+				b = candidate
+				break
+			}
+		}
+		if !ok {
+			t.Fatalf("no bot returned an action for status %q", game.Status)
+		}
+		action.At = testStart
+		next, _, err := engine.Apply(game, action, randGen)
+		if err != nil {
+			t.Fatalf("Apply(%+v) returned error: %v", action, err)
+		}
+		b.Commit(state)
+		game = next
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func TestEasyReturnsLegalActions(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules models.GameRules
+	}{
+		{name: "fair", rules: testRules(models.BiasFair)},
+		{name: "biased", rules: testRules(models.BiasBiased)},
+		{
+			name: "gravity",
+			rules: func() models.GameRules {
+				rules := testRules(models.BiasFair)
+				rules.BoardWidth, rules.BoardHeight, rules.WinLength = 7, 6, 4
+				rules.Variant = models.VariantGravity
+				return rules
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				game := newTestGame(t, tt.rules)
+				bots := []*Bot{New(PlayerID(0), seed, LevelEasy), New(PlayerID(1), seed+100, LevelEasy)}
+				// playRoundはエンジンが拒否したアクションでテストを失敗させる
+				playRound(t, game, bots, rand.New(rand.NewSource(seed)), false)
+			}
+		})
+	}
+}
+
+func TestHardFindsForcedMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		board [][]string
+		want  [2]int
+	}{
+		{
+			name:  "completes its own line",
+			board: [][]string{{"X", "X", ""}, {"O", "O", ""}, {"", "", ""}},
+			want:  [2]int{0, 2},
+		},
+		{
+			name:  "blocks the opponent's line",
+			board: [][]string{{"O", "O", ""}, {"X", "", ""}, {"", "", "X"}},
+			want:  [2]int{0, 2},
+		},
+		{
+			name:  "prefers winning over blocking",
+			board: [][]string{{"O", "O", ""}, {"X", "X", ""}, {"", "", ""}},
+			want:  [2]int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				game := newTestGame(t, testRules(models.BiasFair))
+				game.Board = tt.board
+				action, _, ok := New(PlayerID(0), seed, LevelHard).NextAction(game)
+				if !ok {
+					t.Fatalf("seed %d: NextAction returned no action", seed)
+				}
+				if action.Type != engine.ActionMarkCell || [2]int{action.X, action.Y} != tt.want {
+					t.Errorf("seed %d: NextAction = %s (%d,%d), want mark at %v", seed, action.Type, action.X, action.Y, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSameSeedReplaysTheSameActions(t *testing.T) {
+	levels := []string{LevelEasy, LevelMedium, LevelHard}
+	for _, level := range levels {
+		t.Run(level, func(t *testing.T) {
+			play := func(discard bool) []engine.Action {
+				game := newTestGame(t, testRules(models.BiasBiased))
+				bots := []*Bot{New(PlayerID(0), 7, level), New(PlayerID(1), 8, LevelEasy)}
+				return playRound(t, game, bots, rand.New(rand.NewSource(3)), discard)
+			}
+			first := play(false)
+			if again := play(false); !reflect.DeepEqual(again, first) {
+				t.Errorf("same seed replayed %+v, want %+v", again, first)
+			}
+			// 適用しなかった判断はボットの状態に反映されないため、捨てた判断があっても同じ手順になる
+			if discarded := play(true); !reflect.DeepEqual(discarded, first) {
+				t.Errorf("replay with discarded decisions = %+v, want %+v", discarded, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+)
+
+// LevelEasyのボットが賄賂と糾弾を検討する確率
+const (
+	bribeChance  = 0.25
+	accuseChance = 0.1
+)
+
+// LevelMediumのボットが、審判が中立と推測される時に賄賂を贈る確率
+const mediumBribeChance = 0.5
+
+// 審判の傾きについてのボットの推測。BiasDegreeとFavoredPlayerはプレイヤーに公開されないため、
+// 人間のプレイヤーと同じく、公開されている賄賂の回数と審判の状態の変化から推測する
+type belief struct {
+	round   int
+	favor   int    // 推測した審判の傾き。1は自分（チーム）に有利、-1は他のプレイヤーに有利、0は中立
+	bribes  []int  // 最後に確認した各プレイヤー（チーム戦ではチーム）の賄賂の回数
+	status  string // 最後に確認した審判の状態
+	accused bool   // 自分の糾弾の結果をまだ確認していないかどうか
+}
+
+// 前回確認してからの賄賂の回数と審判の状態の変化を、審判の傾きの推測に反映するヘルパー関数
+func (b *Bot) observe(game *models.Game) {
+	k := &b.state.belief
+	if k.round != game.Round || len(k.bribes) != len(game.BribeCounts) {
+		// ラウンドが変わると審判は中立に戻る
+		*k = belief{round: game.Round, bribes: slices.Clone(game.BribeCounts), status: game.RefereeStatus}
+		return
+	}
+
+	// 賄賂は審判が他のプレイヤーに傾いていれば中立に戻し、そうでなければ贈ったプレイヤーに傾ける
+	own := bribeIndex(game, b.ID)
+	for i, count := range game.BribeCounts {
+		for ; k.bribes[i] < count; k.bribes[i]++ {
+			switch {
+			case i == own && k.favor < 0, i != own && k.favor > 0:
+				k.favor = 0
+			case i == own:
+				k.favor = 1
+			default:
+				k.favor = -1
+			}
+		}
+	}
+
+	// 審判の状態が"normal"から変わった場合は、誰かが糾弾した
+	if isNormal(k.status) && !isNormal(game.RefereeStatus) {
+		sad := strings.HasPrefix(game.RefereeStatus, "sad")
+		switch {
+		case k.accused && sad:
+			// 自分の糾弾が当たり、審判は自分に傾いた
+			k.favor = 1
+		case k.accused:
+			// 自分の糾弾が外れ、審判は次のプレイヤーに傾いた
+			k.favor = -1
+		case sad:
+			// 他のプレイヤーの糾弾が当たった。審判が自分以外に傾いていたなら、糾弾したのはチームの味方
+			if k.favor < 0 && game.Rules.Teams {
+				k.favor = 1
+			} else {
+				k.favor = -1
+			}
+		case len(game.Players) == 2:
+			// 相手の糾弾が外れた。2人対戦では審判は相手の次のプレイヤー、つまり自分に傾く
+			k.favor = 1
+		default:
+			// 誰が糾弾したかは分からないため、審判の傾きも分からない
+			k.favor = 0
+		}
+	}
+	k.accused = false
+	k.status = game.RefereeStatus
+}
+
+// 手番ごとに一度だけ、強さに応じて賄賂を贈るか審判を糾弾するかを検討するヘルパー関数
+func (b *Bot) weigh(game *models.Game) (engine.Action, bool) {
+	current := turn{round: game.Round, moveNumber: len(game.History)}
+	if b.state.weighed == current {
+		return engine.Action{}, false
+	}
+	b.state.weighed = current
+
+	// 審判に不正のないルームや、審判の状態が固定されている（RefereeCountが残っている）間は何もしない
+	if game.Rules.Bias != models.BiasBiased || game.RefereeCount > 0 || !isNormal(game.RefereeStatus) {
+		return engine.Action{}, false
+	}
+	bribe := engine.Action{Type: engine.ActionBribe, PlayerID: b.ID}
+	accuse := engine.Action{Type: engine.ActionAccuse, PlayerID: b.ID}
+	atLimit := b.atBribeLimit(game)
+
+	switch b.Level {
+	case LevelEasy:
+		// 審判の状態を気にせず、気まぐれに賄賂を贈ったり糾弾したりする
+		switch roll := b.randGen.Float64(); {
+		case roll < bribeChance && !atLimit:
+			return bribe, true
+		case roll < bribeChance+accuseChance:
+			b.state.belief.accused = true
+			return accuse, true
+		}
+	case LevelMedium:
+		// 相手の賄賂が見えたら賄賂で打ち消し、賄賂が残っていなければ糾弾する。審判が中立なら時々賄賂を贈る
+		switch {
+		case b.state.belief.favor < 0 && !atLimit:
+			return bribe, true
+		case b.state.belief.favor < 0:
+			b.state.belief.accused = true
+			return accuse, true
+		case b.state.belief.favor == 0 && !atLimit && b.randGen.Float64() < mediumBribeChance:
+			return bribe, true
+		}
+	default:
+		// 相手の賄賂が見えたら、中立に戻すだけの賄賂ではなく糾弾して審判を自分に傾け、状態を固定させる。
+		// 審判が中立なら賄賂を贈るが、賄賂の上限がある場合はどちらかが次の手で列を揃えられる局面まで取っておく
+		switch {
+		case b.state.belief.favor < 0:
+			b.state.belief.accused = true
+			return accuse, true
+		case b.state.belief.favor == 0 && !atLimit && (game.Rules.BribeLimit == 0 || newSearcher(game, b).isCritical()):
+			return bribe, true
+		}
+	}
+	return engine.Action{}, false
+}
+
+// ボット（チーム戦ではボットのチーム）がルールの賄賂の上限に達しているかどうかを返すヘルパー関数
+func (b *Bot) atBribeLimit(game *models.Game) bool {
+	if game.Rules.BribeLimit == 0 {
+		return false
+	}
+	if i := bribeIndex(game, b.ID); i != -1 {
+		return game.BribeCounts[i] >= game.Rules.BribeLimit
+	}
+	return false
+}
+
+// BribeCountsでプレイヤー（チーム戦ではプレイヤーのチーム）の賄賂の回数を数える位置を返すヘルパー関数。見つからない場合は-1
+func bribeIndex(game *models.Game, playerID uint) int {
+	if team := engine.TeamOf(game, playerID); team != -1 {
+		return team
+	}
+	return slices.IndexFunc(game.Players, func(player *models.Player) bool {
+		return player != nil && player.ID == playerID
+	})
+}
+
+// 審判の状態が通常（賄賂と糾弾が有効）かどうかを返すヘルパー関数
+func isNormal(status string) bool {
+	return strings.HasPrefix(status, "normal")
+}
@@ -0,0 +1,415 @@
+package bot
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"time"
+
+	"xicserver/bribe/engine"
+	"xicserver/models"
+)
+
+// 探索の設定。局面の数は時間ではなくengine.Applyの回数で打ち切るため、同じ局面では同じ結果になる
+type searchLimits struct {
+	depth int // 読む手数の上限
+	width int // 各局面で評価値の高い順に読む候補の数。0は全ての候補
+	nodes int // engine.Applyを呼ぶ回数の上限
+}
+
+var (
+	mediumLimits     = searchLimits{depth: 2, width: 10, nodes: 3000}
+	hardLimits       = searchLimits{depth: 4, width: 8, nodes: 6000}
+	exhaustiveLimits = searchLimits{depth: 1 << 10, nodes: 60000}
+)
+
+// LevelHardのボットが最後まで読み切る空のセルの数の上限
+const exhaustiveCells = 10
+
+// 候補が多い盤面では、置かれた印からこの距離以内のセルだけを候補とする
+const (
+	neighborhoodCells = 49
+	neighborhood      = 2
+)
+
+// 評価値で、次に手を打つ側の並びに掛ける倍率
+const tempo = 2
+
+// 勝敗が決まった局面の評価値。早く勝つ手ほど、遅く負ける手ほど高くなるよう残りの手数を加える
+const winScore = 1 << 50
+
+// 1つの手番の探索の状態
+type searcher struct {
+	botID   uint
+	symbol  string
+	limits  searchLimits
+	windows [][][2]int
+	root    *models.Game
+	winners int        // 探索を始めた時点のラウンドの勝者の数。増えた場合は探索中にラウンドが決着した
+	nodes   int        // engine.Applyを呼んだ回数
+	cutoff  bool       // 現在の深さの探索で、決着していない局面を評価値で打ち切ったかどうか
+	randGen *rand.Rand // engine.Applyに渡す乱数。探索の局面では審判が常に選択どおりに置くため結果に影響しない
+	tieGen  *rand.Rand // 評価値が同じ手から選ぶためのボットの乱数
+}
+
+// ボットから見える局面を、審判が常に公平で選択どおりに印を置くものとして探索の準備をするヘルパー関数。
+// engine.Applyは局面を複製してから変更するため、元のゲームは変更されない
+func newSearcher(game *models.Game, b *Bot) *searcher {
+	root := *game
+	root.Board = engine.VisibleBoard(game, b.ID)
+	root.Rules.MarkAccuracy = 1
+	root.Rules.TurnTimeLimit = 0
+	root.BiasDegree, root.FavoredPlayer = 0, 0
+	root.Clocks = nil
+	root.TurnStartedAt = time.Time{}
+	root.DrawOffer, root.UndoRequest = nil, nil
+	// 先手はFirstMoverに記録されていて履歴は探索に使わないため、複製の手間を省く
+	root.History = nil
+	// 同時手番は、ボットが先に置き相手が応じる通常の手番として読む
+	if root.Rules.Simultaneous {
+		root.Rules.Simultaneous = false
+		root.CurrentTurn = b.ID
+		root.PendingMoves = nil
+	}
+
+	limits := mediumLimits
+	if b.Level == LevelHard {
+		limits = hardLimits
+		if root.Quantum == nil && emptyCells(root.Board) <= exhaustiveCells {
+			limits = exhaustiveLimits
+		}
+	}
+	var symbol string
+	if i := slices.IndexFunc(game.Players, func(player *models.Player) bool { return player != nil && player.ID == b.ID }); i != -1 {
+		symbol = game.Players[i].Symbol
+	}
+	return &searcher{
+		botID:   b.ID,
+		symbol:  symbol,
+		limits:  limits,
+		windows: engine.LineWindows(game.Rules),
+		root:    &root,
+		winners: len(game.Winners),
+		randGen: rand.New(rand.NewSource(0)),
+		tieGen:  b.randGen,
+	}
+}
+
+// 探索を1手ずつ深めながら最善の手を返す。局面の数の上限に達した深さの結果は使わない
+func (s *searcher) bestAction() (engine.Action, bool) {
+	actions := s.actions(s.root)
+	if len(actions) == 0 {
+		return engine.Action{}, false
+	}
+	best := actions
+	for depth := 1; depth <= s.limits.depth; depth++ {
+		choices, complete := s.searchRoot(depth)
+		if !complete {
+			break
+		}
+		best = choices
+		// 全ての手を決着まで読み切った場合は、それ以上深く読んでも結果は変わらない
+		if !s.cutoff {
+			break
+		}
+	}
+	return best[s.tieGen.Intn(len(best))], true
+}
+
+// 指定した深さで探索し、評価値が最も高い手を全て返すヘルパー関数。局面の数の上限に達した場合はfalse
+func (s *searcher) searchRoot(depth int) ([]engine.Action, bool) {
+	s.cutoff = false
+	children := s.expand(s.root)
+	if s.nodes >= s.limits.nodes {
+		return nil, false
+	}
+	var best []engine.Action
+	bestScore := -winScore * 2
+	for _, child := range children {
+		// 最善と同じ評価値の手も正確に比べるため、探索の窓の下限を最善の評価値より1つ低くする
+		score := s.minimax(child.game, depth-1, bestScore-1, winScore*2)
+		if s.nodes >= s.limits.nodes {
+			return nil, false
+		}
+		switch {
+		case score > bestScore:
+			bestScore = score
+			best = []engine.Action{child.action}
+		case score == bestScore:
+			best = append(best, child.action)
+		}
+	}
+	return best, len(best) > 0
+}
+
+// 探索した手とその結果の局面
+type child struct {
+	action engine.Action
+	game   *models.Game
+	score  int
+}
+
+// アルファ・ベータ法でボット（チーム戦ではボットのチーム）から見た局面の評価値を返すヘルパー関数。
+// 3人以上の対戦では、他の全てのプレイヤーがボットに不利な手を選ぶものとして読む
+func (s *searcher) minimax(game *models.Game, depth, alpha, beta int) int {
+	if game.Status != engine.StatusInProgress {
+		return s.outcome(game, depth)
+	}
+	if depth == 0 {
+		s.cutoff = true
+		return s.evaluate(game)
+	}
+	children := s.expand(game)
+	if len(children) == 0 {
+		return s.evaluate(game)
+	}
+
+	if s.isAlly(game.CurrentTurn) {
+		best := -winScore * 2
+		for _, child := range children {
+			best = max(best, s.minimax(child.game, depth-1, alpha, beta))
+			alpha = max(alpha, best)
+			if alpha >= beta || s.nodes >= s.limits.nodes {
+				break
+			}
+		}
+		return best
+	}
+	best := winScore * 2
+	for _, child := range children {
+		best = min(best, s.minimax(child.game, depth-1, alpha, beta))
+		beta = min(beta, best)
+		if alpha >= beta || s.nodes >= s.limits.nodes {
+			break
+		}
+	}
+	return best
+}
+
+// 手番のプレイヤーの手を全て適用し、手番のプレイヤーにとって評価値の高い順に返すヘルパー関数。
+// 候補の数が決められている場合は、評価値の高い候補だけを返す
+func (s *searcher) expand(game *models.Game) []child {
+	ally := s.isAlly(game.CurrentTurn)
+	var children []child
+	for _, action := range s.actions(game) {
+		if s.nodes >= s.limits.nodes {
+			break
+		}
+		s.nodes++
+		next, _, err := engine.Apply(game, action, s.randGen)
+		if err != nil {
+			continue
+		}
+		score := s.outcome(next, 0)
+		if next.Status == engine.StatusInProgress {
+			score = s.evaluate(next)
+		}
+		children = append(children, child{action: action, game: next, score: score})
+	}
+	slices.SortStableFunc(children, func(a, b child) int {
+		if ally {
+			return cmp.Compare(b.score, a.score)
+		}
+		return cmp.Compare(a.score, b.score)
+	})
+	if s.limits.width > 0 && len(children) > s.limits.width {
+		children = children[:s.limits.width]
+	}
+	return children
+}
+
+// 局面で手番のプレイヤーが選べる手を返すヘルパー関数。候補が多い盤面では、置かれた印の近くのセルに絞る
+func (s *searcher) actions(game *models.Game) []engine.Action {
+	actions := rootActions(game, game.CurrentTurn)
+	rules := game.Rules
+	if len(actions) <= neighborhoodCells || (rules.Variant != models.VariantStandard && rules.Variant != models.VariantToroidal) {
+		return actions
+	}
+
+	var marks [][2]int
+	for x, row := range game.Board {
+		for y, mark := range row {
+			if mark != "" && mark != engine.ObstacleMark {
+				marks = append(marks, [2]int{x, y})
+			}
+		}
+	}
+	if len(marks) == 0 {
+		// 最初の手は盤面の中央付近に置く
+		marks = [][2]int{{rules.BoardHeight / 2, rules.BoardWidth / 2}}
+	}
+	var near []engine.Action
+	for _, action := range actions {
+		for _, mark := range marks {
+			if distance(rules, [2]int{action.X, action.Y}, mark) <= neighborhood {
+				near = append(near, action)
+				break
+			}
+		}
+	}
+	return near
+}
+
+// ラウンドが決着した局面の評価値を返すヘルパー関数。決着していない場合や引き分けは0
+func (s *searcher) outcome(game *models.Game, depth int) int {
+	if game.Status == engine.StatusInProgress || len(game.Winners) <= s.winners {
+		return 0
+	}
+	switch winnerID := game.Winners[len(game.Winners)-1]; {
+	case winnerID == 0:
+		return 0
+	case s.isAlly(winnerID):
+		return winScore + depth
+	default:
+		return -winScore - depth
+	}
+}
+
+// 決着していない局面の評価値を返すヘルパー関数。揃えられる範囲ごとに、1種類の印だけが並んでいれば
+// その数に応じた点を、自分（チーム）の印なら加え、他のプレイヤーの印なら引く
+func (s *searcher) evaluate(game *models.Game) int {
+	switch {
+	case game.Rules.Variant == models.VariantOrderChaos:
+		// OrderとChaosでは、どちらかの印だけが並ぶ範囲が多いほどOrderに有利
+		score := 0
+		for _, window := range s.windows {
+			if symbol, count := windowMarks(game.Board, window); symbol != "" {
+				score += windowScore(count)
+			}
+		}
+		if !s.isAlly(engine.OrderPlayer(game)) {
+			score = -score
+		}
+		return score
+	case game.Ultimate != nil:
+		// アルティメット三目並べでは、決着していない小盤面の範囲と、獲得した小盤面の並びを評価する
+		subSize := len(game.Board) / len(game.Ultimate.SubBoards)
+		score := 0
+		for _, window := range s.windows {
+			if sub := window[0]; game.Ultimate.SubBoards[sub[0]/subSize][sub[1]/subSize] == "" {
+				score += s.windowValue(game.Board, window)
+			}
+		}
+		meta := engine.LineWindows(models.GameRules{BoardWidth: subSize, BoardHeight: subSize, WinLength: subSize})
+		for _, window := range meta {
+			score += s.windowValue(game.Ultimate.SubBoards, window) * windowScore(subSize)
+		}
+		return score
+	}
+
+	// 次に手を打つ側は並んだ印を先に伸ばせるため、その側の範囲を高く評価する
+	allies, rivals := 0, 0
+	for _, window := range s.windows {
+		if value := s.windowValue(game.Board, window); value > 0 {
+			allies += value
+		} else {
+			rivals -= value
+		}
+	}
+	if s.isAlly(game.CurrentTurn) {
+		allies *= tempo
+	} else {
+		rivals *= tempo
+	}
+	score := allies - rivals
+	// ミゼールでは列を揃えたプレイヤーの負け
+	if game.Rules.Misere {
+		score = -score
+	}
+	return score
+}
+
+// 揃えられる範囲の評価値を返すヘルパー関数
+func (s *searcher) windowValue(board [][]string, window [][2]int) int {
+	symbol, count := windowMarks(board, window)
+	switch {
+	case symbol == "":
+		return 0
+	case symbol == s.symbol:
+		return windowScore(count)
+	default:
+		return -windowScore(count)
+	}
+}
+
+// 範囲に並んだ印が1種類だけの場合に、その印と数を返すヘルパー関数。
+// 印がない場合や、複数の種類の印、プレイヤーの印ではないもの（障害物、引き分けの小盤面）を含む場合は空文字列
+func windowMarks(board [][]string, window [][2]int) (string, int) {
+	symbol, count := "", 0
+	for _, cell := range window {
+		mark := board[cell[0]][cell[1]]
+		switch {
+		case mark == "":
+			continue
+		case !slices.Contains(engine.PlayerSymbols, mark), symbol != "" && mark != symbol:
+			return "", 0
+		}
+		symbol = mark
+		count++
+	}
+	return symbol, count
+}
+
+// 範囲に並んだ印の数に応じた点を返すヘルパー関数。1つ多く並ぶごとに8倍になる
+func windowScore(count int) int {
+	return 1 << (3 * min(count, 15))
+}
+
+// isCritical はボットかボット以外のプレイヤーが、次の手で列を揃えられる局面かどうかを返す
+func (s *searcher) isCritical() bool {
+	for _, player := range s.root.Players {
+		if player == nil {
+			continue
+		}
+		game := *s.root
+		game.CurrentTurn = player.ID
+		if game.Quantum != nil && game.Quantum.Collapse != nil {
+			continue
+		}
+		for _, action := range s.actions(&game) {
+			next, _, err := engine.Apply(&game, action, s.randGen)
+			if err == nil && next.Status != engine.StatusInProgress && len(next.Winners) > s.winners && next.Winners[len(next.Winners)-1] != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// プレイヤーがボット自身か、チーム戦でボットと同じチームかどうかを返すヘルパー関数
+func (s *searcher) isAlly(playerID uint) bool {
+	if playerID == s.botID {
+		return true
+	}
+	team := engine.TeamOf(s.root, s.botID)
+	return team != -1 && engine.TeamOf(s.root, playerID) == team
+}
+
+// 盤面の空のセルの数を返すヘルパー関数
+func emptyCells(board [][]string) int {
+	count := 0
+	for _, row := range board {
+		for _, mark := range row {
+			if mark == "" {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// 2つのセルの縦横斜めの距離を返すヘルパー関数。端がつながった盤面では端をまたいだ距離を使う
+func distance(rules models.GameRules, a, b [2]int) int {
+	dx, dy := abs(a[0]-b[0]), abs(a[1]-b[1])
+	if rules.Variant == models.VariantToroidal {
+		dx, dy = min(dx, rules.BoardHeight-dx), min(dy, rules.BoardWidth-dy)
+	}
+	return max(dx, dy)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -2,7 +2,11 @@ package broadcast
 
 import (
 	"encoding/json"
+	"slices"
+	"strings"
+	"time"
 
+	"xicserver/bribe/engine"
 	"xicserver/models"
 
 	"go.uber.org/zap"
@@ -10,8 +14,21 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// ゲームの状態をブロードキャストするヘルパー関数
+// BroadcastGameState は盤面全体を含むゲームの状態をブロードキャストします。
+// プレイヤーの参加や再接続の時に使い、クライアントの盤面を送信した盤面に置き換えさせます。
 func BroadcastGameState(game *models.Game, logger *zap.Logger) {
+	broadcastGameState(nil, game, logger)
+}
+
+// BroadcastGameStateChanges はアクションを適用した後のゲームの状態をブロードキャストします。
+// 大きい盤面では、適用前の状態と比べて変わったセルのリストだけを"boardChanges"に格納し、変更がない場合は空のリストとなります。
+// 小さい盤面では従来どおり盤面全体を送信します。
+func BroadcastGameStateChanges(previous *models.Game, game *models.Game, logger *zap.Logger) {
+	broadcastGameState(previous, game, logger)
+}
+
+// ゲームの状態をブロードキャストするヘルパー関数。previousがnilの場合は盤面全体を送信する
+func broadcastGameState(previous *models.Game, game *models.Game, logger *zap.Logger) {
 	playersInfo := make([]map[string]interface{}, len(game.Players))
 	var currentPlayer string
 	for i, player := range game.Players {
@@ -20,6 +37,8 @@ func BroadcastGameState(game *models.Game, logger *zap.Logger) {
 				"id":       player.ID,
 				"nickName": player.NickName,
 				"symbol":   player.Symbol,
+				"team":     engine.TeamOf(game, player.ID),
+				"bot":      player.Bot,
 			}
 			if player.ID == game.CurrentTurn {
 				currentPlayer = player.NickName // 現在のターンのプレイヤーのニックネームを設定
@@ -29,20 +48,41 @@ func BroadcastGameState(game *models.Game, logger *zap.Logger) {
 
 	gameState := map[string]interface{}{
 		"type":          "gameState",
-		"board":         game.Board,
 		"currentPlayer": currentPlayer,
 		"status":        game.Status,
+		"round":         game.Round,
 		"playersOnline": game.PlayersOnlineStatus,
 		"playersInfo":   playersInfo,
-		"bias":          game.Bias,
+		"bias":          game.Rules.Bias,
 		"refereeStatus": game.RefereeStatus,
 		"winners":       game.Winners,
+		"matchWinner":   game.MatchWinner,
 		"bribeCounts":   game.BribeCounts,
+		"turnTimeLimit": game.Rules.TurnTimeLimit,
+		"turnTimeLeft":  turnTimeLeft(game),
+		"clocks":        clocksLeft(game),
+		"drawOffer":     game.DrawOffer,
+		"undoRequest":   game.UndoRequest,
+		"canUndo":       game.Rules.AllowUndo,
+		"moveNumber":    len(game.History),
+		"variant":       game.Rules.Variant,
+		"ultimate":      game.Ultimate,
+		"quantum":       game.Quantum,
+		"orderPlayer":   engine.OrderPlayer(game),
+		"fog":           game.Rules.Fog,
+		"hands":         game.Hands,
+		"shielded":      game.Shielded,
+		"teams":         game.Rules.Teams,
+		"simultaneous":  game.Rules.Simultaneous,
+		"waitingFor":    waitingFor(game),
+		"lastMoves":     game.LastMoves,
+		"roundResigned": game.RoundResigned,
 	}
-	messageJSON, _ := json.Marshal(gameState)
 
+	// 盤面と最後の手はプレイヤーごとに見える範囲が異なるため、宛先ごとにメッセージを作る
 	for _, player := range game.Players {
-		if player != nil {
+		if player != nil && player.Conn != nil {
+			messageJSON, _ := json.Marshal(viewFor(gameState, previous, game, player.ID))
 			if err := player.Conn.WriteMessage(websocket.TextMessage, messageJSON); err != nil {
 				logger.Error("Failed to broadcast game state", zap.Error(err))
 			}
@@ -50,6 +90,136 @@ func BroadcastGameState(game *models.Game, logger *zap.Logger) {
 	}
 }
 
+// BroadcastWaitingForMoves は同時手番で、提出を終えたプレイヤーとまだ提出していないプレイヤーを通知する。
+// 提出されたセルは全員の提出が揃うまで公開しない
+func BroadcastWaitingForMoves(game *models.Game, logger *zap.Logger) {
+	waiting := waitingFor(game)
+	committed := make([]uint, 0, len(game.PendingMoves))
+	for _, player := range game.Players {
+		if player != nil && !slices.Contains(waiting, player.ID) {
+			committed = append(committed, player.ID)
+		}
+	}
+	message := map[string]interface{}{
+		"type":       "waitingForMoves",
+		"round":      game.Round,
+		"moveNumber": len(game.History),
+		"committed":  committed,
+		"waitingFor": waiting,
+	}
+	messageJSON, _ := json.Marshal(message)
+	for _, player := range game.Players {
+		if player != nil && player.Conn != nil {
+			if err := player.Conn.WriteMessage(websocket.TextMessage, messageJSON); err != nil {
+				logger.Error("Failed to broadcast waiting state", zap.Error(err))
+			}
+		}
+	}
+}
+
+// 同時手番の進行中に、まだセルを提出していないプレイヤーのIDを返すヘルパー関数。それ以外はnil
+func waitingFor(game *models.Game) []uint {
+	if !game.Rules.Simultaneous || game.Status != engine.StatusInProgress {
+		return nil
+	}
+	return engine.WaitingFor(game)
+}
+
+// 共通のメッセージに、プレイヤーから見える盤面と最後の手を加えたメッセージを返すヘルパー関数。
+// 大きい盤面でpreviousがある場合は、プレイヤーから見える盤面のうち変わったセルだけを"boardChanges"に加える
+func viewFor(message map[string]interface{}, previous *models.Game, game *models.Game, viewerID uint) map[string]interface{} {
+	view := make(map[string]interface{}, len(message)+2)
+	for key, value := range message {
+		view[key] = value
+	}
+	board := engine.VisibleBoard(game, viewerID)
+	if previous != nil && isLargeBoard(board) {
+		if changes, ok := engine.ChangedCells(game.Rules, engine.VisibleBoard(previous, viewerID), board); ok {
+			putBoardShape(view, game)
+			view["boardChanges"] = changes
+		} else {
+			putBoard(view, game, board)
+		}
+	} else {
+		putBoard(view, game, board)
+	}
+	view["lastMove"] = engine.VisibleLastMove(game, viewerID)
+	return view
+}
+
+// 盤面のセル数がこれを超える場合は、盤面を行ごとの文字列に圧縮し、対局中の更新では変わったセルだけを送信する
+const compactBoardCells = 100
+
+// 盤面のセル数がcompactBoardCellsを超えるかどうかを返すヘルパー関数
+func isLargeBoard(board [][]string) bool {
+	return len(board) > 0 && len(board)*len(board[0]) > compactBoardCells
+}
+
+// 盤面の描画方法をメッセージに追加するヘルパー関数。
+// 端がつながった盤面では"wrap"を、3次元の盤面では"layered"をtrueにする
+func putBoardShape(message map[string]interface{}, game *models.Game) {
+	message["wrap"] = game.Rules.Variant == models.VariantToroidal
+	message["layered"] = game.Rules.Variant == models.VariantCube
+}
+
+// 盤面全体をメッセージに追加するヘルパー関数。
+// 小さい盤面は従来どおり"board"に[][]stringで、大きい盤面は"boardRows"に1行1文字列（空のセルは"."）で格納する。
+// 3次元の盤面は"layers"に層ごとの[][]stringで格納する
+func putBoard(message map[string]interface{}, game *models.Game, board [][]string) {
+	putBoardShape(message, game)
+	if layers := engine.BoardLayers(game.Rules, board); layers != nil {
+		message["layers"] = layers
+		return
+	}
+	if !isLargeBoard(board) {
+		message["board"] = board
+		return
+	}
+	rows := make([]string, len(board))
+	for i, row := range board {
+		var b strings.Builder
+		for _, cell := range row {
+			if cell == "" {
+				cell = "."
+			}
+			b.WriteString(cell)
+		}
+		rows[i] = b.String()
+	}
+	message["boardRows"] = rows
+}
+
+// 現在の手番の残り時間（ミリ秒）を返すヘルパー関数。制限時間がない場合は0
+func turnTimeLeft(game *models.Game) int64 {
+	if game.TurnDeadline.IsZero() {
+		return 0
+	}
+	return max(time.Until(game.TurnDeadline).Milliseconds(), 0)
+}
+
+// 各プレイヤーの残りの持ち時間（ミリ秒）を返すヘルパー関数。手番中のプレイヤーは経過時間を差し引く
+func clocksLeft(game *models.Game) map[uint]int64 {
+	if game.Clocks == nil {
+		return nil
+	}
+	clocks := make(map[uint]int64, len(game.Clocks))
+	for playerID, remaining := range game.Clocks {
+		if playerID == game.CurrentTurn && !game.TurnStartedAt.IsZero() {
+			remaining -= time.Since(game.TurnStartedAt)
+		}
+		clocks[playerID] = max(remaining.Milliseconds(), 0)
+	}
+	return clocks
+}
+
+// 直近のラウンドの勝利を決めた列を返すヘルパー関数
+func lastWinningLine(game *models.Game) *models.WinLine {
+	if len(game.WinningLines) == 0 {
+		return nil
+	}
+	return game.WinningLines[len(game.WinningLines)-1]
+}
+
 func BroadcastResults(game *models.Game, logger *zap.Logger) {
 	playersInfo := make([]map[string]interface{}, len(game.Players))
 	for i, player := range game.Players {
@@ -58,6 +228,8 @@ func BroadcastResults(game *models.Game, logger *zap.Logger) {
 				"id":       player.ID,
 				"nickName": player.NickName,
 				"symbol":   player.Symbol,
+				"team":     engine.TeamOf(game, player.ID),
+				"bot":      player.Bot,
 			}
 		}
 	}
@@ -65,15 +237,26 @@ func BroadcastResults(game *models.Game, logger *zap.Logger) {
 	results := map[string]interface{}{
 		"type":          "gameResults",
 		"bribeCounts":   game.BribeCounts,
-		"board":         game.Board,
 		"currentTurn":   game.CurrentTurn,
 		"status":        game.Status,
+		"round":         game.Round,
 		"playersOnline": game.PlayersOnlineStatus,
 		"playersInfo":   playersInfo,
-		"bias":          game.Bias,
+		"bias":          game.Rules.Bias,
 		"refereeStatus": game.RefereeStatus,
 		"winners":       game.Winners,
+		"matchWinner":   game.MatchWinner,
+		"finishReason":  game.FinishReason,
+		"winningLine":   lastWinningLine(game),
+		"winningLines":  game.WinningLines,
+		"clocks":        clocksLeft(game),
+		"lastMove":      game.LastMove,
+		"variant":       game.Rules.Variant,
+		"ultimate":      game.Ultimate,
+		"quantum":       game.Quantum,
 	}
+	// 結果では霧のルールでも全ての印を公開する
+	putBoard(results, game, game.Board)
 	resultsJSON, err := json.Marshal(results)
 	if err != nil {
 		logger.Error("Failed to marshal game results", zap.Error(err))
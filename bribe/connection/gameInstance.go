@@ -2,10 +2,16 @@ package connection
 
 import (
 	"context"
-	"math/rand"
+	"errors"
+	"slices"
+	"sync"
+	"time"
 
 	"xicserver/bribe"
+	"xicserver/bribe/bot"
 	"xicserver/bribe/broadcast"
+	"xicserver/bribe/engine"
+	"xicserver/bribe/themes"
 	"xicserver/models"
 
 	"go.uber.org/zap"
@@ -19,6 +25,10 @@ func ManageGameInstance(ctx context.Context, db *gorm.DB, logger *zap.Logger, ga
 	if existingGame, ok := games[client.RoomID]; ok {
 		// ゲームインスタンスが既に存在する場合、参加
 		game := existingGame
+		// タイマーやボットのゴルーチンと競合しないように、参加処理の間はゲームのロックを取得する
+		game.Mu.Lock()
+		defer game.Mu.Unlock()
+
 		alreadyJoined := false
 		playerIndex := -1
 		for i, player := range game.Players {
@@ -33,82 +43,93 @@ func ManageGameInstance(ctx context.Context, db *gorm.DB, logger *zap.Logger, ga
 			game.PlayersOnlineStatus[client.UserID] = true // オンライン状態をtrueに更新
 			logger.Info("Player rejoined the game", zap.Uint("UserID", client.UserID), zap.Uint("RoomID", client.RoomID))
 		} else {
+			// 空いている最初の枠に参加する
+			slot := slices.Index(game.Players, nil)
+			if slot == -1 {
+				logger.Warn("Game is already full", zap.Uint("UserID", client.UserID), zap.Uint("RoomID", client.RoomID))
+				return nil, errors.New("game is already full")
+			}
 			var challenger models.Challenger
 			db.Where("game_room_id = ? AND user_id = ?", client.RoomID, client.UserID).First(&challenger)
-			nickName := challenger.ChallengerNickname // ニックネームを取得
-			symbol := "O"                             // 2人目のプレイヤーには "O" を割り当て
-			game.Players[1] = &models.Player{ID: client.UserID, Conn: conn, Symbol: symbol, NickName: nickName}
-			game.PlayersOnlineStatus[1] = true // 2人目のプレイヤーをオンラインとしてマーク
-			logger.Info("Second player joined the game", zap.Uint("UserID", client.UserID), zap.Uint("RoomID", client.RoomID))
+			nickName := challenger.ChallengerNickname     // ニックネームを取得
+			symbol := engine.SeatSymbol(game.Rules, slot) // 参加順に "O"、"Δ" を割り当て。チーム戦では席の偶奇で "X" か "O"
+			game.Players[slot] = &models.Player{ID: client.UserID, Conn: conn, Symbol: symbol, NickName: nickName}
+			game.PlayersOnlineStatus[client.UserID] = true // 参加したプレイヤーをオンラインとしてマーク
+			logger.Info("Player joined the game", zap.Uint("UserID", client.UserID), zap.Uint("RoomID", client.RoomID), zap.Int("Slot", slot))
 
-			// 2人目のプレイヤーが参加したので、ランダムに先手を決定
-			if randGen.Intn(2) == 0 {
-				game.CurrentTurn = game.Players[0].ID
-			} else {
-				game.CurrentTurn = game.Players[1].ID
+			// 全てのプレイヤーが揃ったら、ランダムに先手を決定し、持ち時間と手番の制限時間を開始
+			if !slices.Contains(game.Players, nil) {
+				engine.StartMatch(game, game.Players[randGen.Intn(len(game.Players))].ID, time.Now())
+				logger.Info("Turn decided", zap.Uint("CurrentTurn", game.CurrentTurn))
 			}
-			logger.Info("Turn decided", zap.Uint("CurrentTurn", game.CurrentTurn))
 		}
 		broadcast.BroadcastGameState(game, logger)
 		logger.Info("Game state broadcasted", zap.Uint("RoomID", client.RoomID))
 		return game, nil
 	} else {
-		var boardSize int
-		var roomTheme string
 		var gameRoom models.GameRoom
-		var bias string // "fair"か"biased"
 
 		err := db.Where("id = ?", client.RoomID).First(&gameRoom).Error
 		if err != nil {
 			logger.Error("Failed to retrieve game room from database", zap.Error(err))
 			return nil, err
 		}
-		roomTheme = gameRoom.RoomTheme
+		roomTheme := gameRoom.RoomTheme
 		nickName := gameRoom.RoomCreator // ニックネームを取得
-		// RoomThemeに基づいて盤面のサイズと不正度合いを設定
-		switch roomTheme {
-		case "3x3_biased":
-			boardSize = 3
-			bias = "biased"
-		case "5x5_biased":
-			boardSize = 5
-			bias = "biased"
-		default:
-			boardSize = 3
-			bias = "fair"
-		}
+		// ルーム作成時に確定したルールから盤面のサイズや不正の有無などを取得
+		rules := themes.RulesForRoom(gameRoom)
 
-		board := make([][]string, boardSize)
-		for i := range board {
-			board[i] = make([]string, boardSize)
-		}
-
-		symbol := "X"
 		game := &models.Game{
 			ID:                  client.RoomID,
-			Board:               board,
-			Players:             [2]*models.Player{{ID: client.UserID, Conn: conn, Symbol: symbol, NickName: nickName}, nil},
-			Status:              "round1",
+			Players:             make([]*models.Player, engine.PlayerCount(rules)), // ルールの人数分の枠を用意
+			Status:              engine.StatusInProgress,
+			Round:               1,
 			RoomTheme:           roomTheme,
-			Bias:                bias,
+			Rules:               rules,
 			BiasDegree:          0,
-			RefereeStatus:       getRandomNormalRefereeStatus(randGen),
+			RefereeStatus:       engine.RandomNormalRefereeStatus(randGen),
 			PlayersOnlineStatus: make(map[uint]bool), // マップを初期化
-			BribeCounts:         [2]int{0, 0},
+			BribeCounts:         make([]int, engine.PlayerCount(rules)),
+			Mu:                  &sync.Mutex{},
+		}
+		// gamesに登録した時点で他のクライアントから参照されるため、準備が終わるまでロックを取得する
+		game.Mu.Lock()
+		defer game.Mu.Unlock()
+		// ルールのバリエーションに合わせて盤面と障害物を用意
+		if err := engine.ResetBoard(game, randGen); err != nil {
+			logger.Warn("Game started without obstacles", zap.Uint("RoomID", client.RoomID), zap.Error(err))
 		}
 		games[client.RoomID] = game
-		game.Players[0] = &models.Player{ID: client.UserID, Conn: conn, Symbol: "X", NickName: nickName}
+		game.Players[0] = &models.Player{ID: client.UserID, Conn: conn, Symbol: engine.SeatSymbol(rules, 0), NickName: nickName}
 		game.PlayersOnlineStatus[client.UserID] = true // 初期プレイヤーをオンラインとしてマーク
 		logger.Info("New game instance created", zap.Uint("RoomID", client.RoomID), zap.Uint("UserID", client.UserID))
 
+		// ボットと対戦するルームでは、作成者以外の席をボットで埋めてすぐに対戦を始める
+		// シードが指定されていない場合はここで決め、ログに残した値を指定すれば同じボットの判断を再現できる
+		if gameRoom.VsBot {
+			seed := gameRoom.BotSeed
+			if seed == 0 {
+				seed = randGen.Int63()
+			}
+			for seat := 1; seat < len(game.Players); seat++ {
+				botID := bot.PlayerID(seat)
+				game.Players[seat] = &models.Player{
+					ID:       botID,
+					Symbol:   engine.SeatSymbol(rules, seat),
+					NickName: bot.NickName,
+					Bot:      true,
+					BotLevel: gameRoom.BotLevel,
+					BotSeed:  seed + int64(seat),
+				}
+				game.PlayersOnlineStatus[botID] = true
+			}
+			engine.StartMatch(game, game.Players[randGen.Intn(len(game.Players))].ID, time.Now())
+			logger.Info("Bots joined the game", zap.Uint("RoomID", client.RoomID), zap.String("botLevel", gameRoom.BotLevel), zap.Int64("botSeed", seed), zap.Uint("CurrentTurn", game.CurrentTurn))
+		}
+
 		broadcast.BroadcastGameState(game, logger)
 		logger.Info("Game state broadcasted", zap.Uint("RoomID", client.RoomID))
 
 		return game, nil
 	}
 }
-
-func getRandomNormalRefereeStatus(randGen *rand.Rand) string {
-	normalStatuses := []string{"normal_01", "normal_02", "normal_03", "normal_04", "normal_05", "normal_06", "normal_07"}
-	return normalStatuses[randGen.Intn(len(normalStatuses))]
-}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"xicserver/models"
+
+	"gorm.io/gorm"
+)
+
+// FinishGameRoom はゲーム終了時にGameRoomのgame_stateを"finished"に更新し、作成者と承認済み申請者のフラグを解除します。
+func FinishGameRoom(db *gorm.DB, roomID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Update game state in the database
+		if err := tx.Model(&models.GameRoom{}).Where("id = ?", roomID).Update("game_state", "finished").Error; err != nil {
+			return err
+		}
+
+		// Update the room creator's HasRoom to false
+		var gameRoom models.GameRoom
+		if err := tx.Where("id = ?", roomID).First(&gameRoom).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", gameRoom.UserID).Update("has_room", false).Error; err != nil {
+			return err
+		}
+
+		// Find all users with 'accepted' requests for this room and update their HasRequest to false
+		var challengers []models.Challenger
+		if err := tx.Where("game_room_id = ? AND status = 'accepted'", gameRoom.ID).Find(&challengers).Error; err != nil {
+			return err
+		}
+
+		for _, challenger := range challengers {
+			if err := tx.Model(&models.User{}).Where("id = ?", challenger.UserID).Update("has_request", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// NewBoard はルール設定に従って空の盤面を作成する。Board[x][y]のxが行、yが列
+func NewBoard(rules models.GameRules) [][]string {
+	board := make([][]string, rules.BoardHeight)
+	for i := range board {
+		board[i] = make([]string, rules.BoardWidth)
+	}
+	return board
+}
+
+// 盤面の複製を返すヘルパー関数
+func copyBoard(board [][]string) [][]string {
+	copied := make([][]string, len(board))
+	for i, row := range board {
+		copied[i] = append([]string(nil), row...)
+	}
+	return copied
+}
+
+// CellChange は盤面の差分として送信する1つのセルの変更
+type CellChange struct {
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Z    int    `json:"z,omitempty"` // 3次元の盤面の層
+	Mark string `json:"mark"`        // 変更後の印。空のセルになった場合は空文字列
+}
+
+// ChangedCells は2つの盤面で異なるセルを、プレイヤーから見た行・列・層で返す。盤面の形が異なる場合はfalse
+func ChangedCells(rules models.GameRules, before, after [][]string) ([]CellChange, bool) {
+	if len(before) != len(after) {
+		return nil, false
+	}
+	changes := []CellChange{}
+	for i, row := range after {
+		if len(before[i]) != len(row) {
+			return nil, false
+		}
+		for j, mark := range row {
+			if before[i][j] != mark {
+				x, y, z := boardCoords(rules, [2]int{i, j})
+				changes = append(changes, CellChange{X: x, Y: y, Z: z, Mark: mark})
+			}
+		}
+	}
+	return changes, true
+}
+
+// 指定されたセルを除いた空のセルのリストを返すヘルパー関数
+func getEmptyCellsExcept(board [][]string, excludeX, excludeY int) [][2]int {
+	var emptyCells [][2]int
+	for i, row := range board {
+		for j, cell := range row {
+			if cell == "" && !(i == excludeX && j == excludeY) {
+				emptyCells = append(emptyCells, [2]int{i, j})
+			}
+		}
+	}
+	return emptyCells
+}
+
+// マス目がすべて埋まっているかどうかの確認
+func isBoardFull(board [][]string) bool {
+	for _, row := range board {
+		for _, cell := range row {
+			if cell == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
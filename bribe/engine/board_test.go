@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestChangedCells(t *testing.T) {
+	cubeRules := models.GameRules{Variant: models.VariantCube, BoardWidth: 4, BoardHeight: 4, BoardDepth: 4}
+	cubeRows := func(marks map[[2]int]string) []string {
+		rows := make([]string, 16)
+		for i := range rows {
+			row := []byte(strings.Repeat(".", 4))
+			for cell, mark := range marks {
+				if cell[0] == i {
+					row[cell[1]] = mark[0]
+				}
+			}
+			rows[i] = string(row)
+		}
+		return rows
+	}
+	tests := []struct {
+		name   string
+		rules  models.GameRules
+		before [][]string
+		after  [][]string
+		want   []CellChange
+		wantOK bool
+	}{
+		{
+			name:   "no change",
+			before: parseBoard("X..", "...", "..."),
+			after:  parseBoard("X..", "...", "..."),
+			want:   []CellChange{},
+			wantOK: true,
+		},
+		{
+			name:   "placed mark",
+			before: parseBoard("X..", "...", "..."),
+			after:  parseBoard("X..", ".O.", "..."),
+			want:   []CellChange{{X: 1, Y: 1, Mark: "O"}},
+			wantOK: true,
+		},
+		{
+			name:   "removed mark is sent as an empty cell",
+			before: parseBoard("X..", ".O.", "..."),
+			after:  parseBoard("X..", "...", "..O"),
+			want:   []CellChange{{X: 1, Y: 1, Mark: ""}, {X: 2, Y: 2, Mark: "O"}},
+			wantOK: true,
+		},
+		{
+			name:   "cube cell reported with its layer",
+			rules:  cubeRules,
+			before: parseBoard(cubeRows(nil)...),
+			after:  parseBoard(cubeRows(map[[2]int]string{{9, 3}: "X"})...),
+			want:   []CellChange{{X: 1, Y: 3, Z: 2, Mark: "X"}},
+			wantOK: true,
+		},
+		{
+			name:   "different number of rows",
+			before: parseBoard("...", "..."),
+			after:  parseBoard("...", "...", "..."),
+		},
+		{
+			name:   "different number of columns",
+			before: parseBoard("...", "..."),
+			after:  parseBoard("....", "...."),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ChangedCells(tt.rules, tt.before, tt.after)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangedCells() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+
+	"xicserver/models"
+)
+
+func applyBribe(game *models.Game, action Action) ([]Event, error) {
+	// 審判に不正のないルームでは賄賂を受け付けない
+	if game.Rules.Bias != models.BiasBiased {
+		return nil, ErrBribesDisabled
+	}
+	// RefereeStatusが"normal"以外で始まる場合は賄賂を無視
+	if !strings.HasPrefix(game.RefereeStatus, "normal") {
+		return []Event{messageTo(action.PlayerID, "SYSTEM: Bribe ignored, referee status is not normal")}, nil
+	}
+
+	// 賄賂を贈ったプレイヤーを特定。チーム戦ではチームで賄賂の回数と審判の有利不利を共有する
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	briberID := teamLeader(game, action.PlayerID)
+	briberIndex := playerIndex(game, briberID)
+
+	// ルールで賄賂の上限が決められている場合は、上限に達した賄賂を無視
+	if game.Rules.BribeLimit > 0 && game.BribeCounts[briberIndex] >= game.Rules.BribeLimit {
+		return []Event{messageTo(action.PlayerID, "SYSTEM: Bribe ignored, you have reached the bribe limit")}, nil
+	}
+
+	// 賄賂回数のインクリメント
+	game.BribeCounts[briberIndex] += 1
+
+	// 審判が他のプレイヤーに傾いていれば中立に戻し、そうでなければ賄賂を贈ったプレイヤーに傾ける
+	if game.BiasDegree > 0 && game.FavoredPlayer != briberID {
+		game.BiasDegree--
+		if game.BiasDegree == 0 {
+			game.FavoredPlayer = 0
+		}
+	} else {
+		game.BiasDegree = 1 // 不正度合いは1を超えない
+		game.FavoredPlayer = briberID
+	}
+
+	return []Event{
+		messageTo(action.PlayerID, "REFEREE: Your Bribe accepted!"),
+		{Type: EventGameState},
+	}, nil
+}
+
+func applyAccuse(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	// 審判に不正のないルームでは糾弾を受け付けない
+	if game.Rules.Bias != models.BiasBiased {
+		return nil, ErrBribesDisabled
+	}
+	// 審判の状態が "normal" で始まる場合は糾弾は無効
+	if !strings.HasPrefix(game.RefereeStatus, "normal") {
+		return []Event{messageTo(action.PlayerID, "SYSTEM: Accusation is ineffective!")}, nil
+	}
+
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	// チーム戦ではどちらのプレイヤーもチームとして糾弾できる
+	accuserID := teamLeader(game, action.PlayerID)
+
+	// 他のプレイヤーが賄賂を贈っていたかどうか判定し、対応する処理を実行
+	// 一行目は審判が公平（BiasDegreeが"0"）だった場合
+	if game.BiasDegree == 0 {
+		game.RefereeStatus = getRandomAngryRefereeStatus(randGen)
+		game.BiasDegree = 1
+		game.FavoredPlayer = teamLeader(game, nextPlayerID(game, accuserID)) // 糾弾したプレイヤーの次のプレイヤーに有利にする
+	} else if game.FavoredPlayer != accuserID {
+		// 他のプレイヤーが賄賂を贈っていた場合
+		briberID := game.FavoredPlayer
+		game.RefereeStatus = getRandomSadRefereeStatus(randGen)
+		game.FavoredPlayer = accuserID // 糾弾したプレイヤーに有利にする
+		// 霧のルールでは、審判が賄賂を贈ったプレイヤーの最後の印の位置を明かす
+		if game.Rules.Fog {
+			revealLastMarkOf(game, briberID)
+		}
+	} else {
+		// 賄賂を贈っていたのが自分だった場合
+		game.RefereeStatus = getRandomAngryRefereeStatus(randGen)
+		game.FavoredPlayer = teamLeader(game, nextPlayerID(game, accuserID)) // 既に自分に有利なので次のプレイヤーに有利にする
+	}
+	game.RefereeCount = 4 // 4手の間、審判の状態を固定する
+
+	// 審判の状態に応じたシステムチャットメッセージを送信
+	var events []Event
+	if strings.HasPrefix(game.RefereeStatus, "angry") {
+		events = append(events, messageAll("REFEREE: Wrong accusation! I'm angry!"))
+	} else if strings.HasPrefix(game.RefereeStatus, "sad") {
+		events = append(events, messageAll("REFEREE: Sorry I'm regret..."))
+	}
+
+	return append(events, Event{Type: EventGameState}), nil
+}
+
+// 審判の不正がプレイヤーに与える影響を返すヘルパー関数。
+// 有利に扱われるプレイヤーは正、それ以外のプレイヤーは負、審判が公平なら0
+func biasAdvantage(game *models.Game, playerID uint) int {
+	if game.FavoredPlayer == teamLeader(game, playerID) {
+		return game.BiasDegree
+	}
+	return -game.BiasDegree
+}
+
+// RandomNormalRefereeStatus は通常状態の審判の表情をランダムに返す
+func RandomNormalRefereeStatus(randGen *rand.Rand) string {
+	normalStatuses := []string{"normal_01", "normal_02", "normal_03", "normal_04", "normal_05", "normal_06", "normal_07"}
+	return normalStatuses[randGen.Intn(len(normalStatuses))]
+}
+
+func getRandomAngryRefereeStatus(randGen *rand.Rand) string {
+	angryStatuses := []string{"angry_01", "angry_02", "angry_03", "angry_04", "angry_05"}
+	return angryStatuses[randGen.Intn(len(angryStatuses))]
+}
+
+func getRandomSadRefereeStatus(randGen *rand.Rand) string {
+	sadStatuses := []string{"sad_01", "sad_02", "sad_03", "sad_04"}
+	return sadStatuses[randGen.Intn(len(sadStatuses))]
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// MoveCandidates はプレイヤーが現在の盤面で選べるmarkCellのアクションを返す。
+// 座標はクライアントが送る形式（3次元の盤面では層、重力ルールでは列）に合わせ、
+// OrderとChaosでは印ごと、量子三目並べでは2つのセルの組ごとに別のアクションとなる。ボットなどサーバー側のプレイヤーが使う
+func MoveCandidates(game *models.Game, playerID uint) []Action {
+	cells := variantFor(game.Rules).candidates(game, playerID)
+
+	var actions []Action
+	if game.Rules.Variant == models.VariantQuantum {
+		// 確定していないセルが1つだけなら、そのセルに確定した印を置く
+		if len(cells) == 1 {
+			return []Action{{Type: ActionMarkCell, PlayerID: playerID, X: cells[0][0], Y: cells[0][1], X2: cells[0][0], Y2: cells[0][1]}}
+		}
+		for i, first := range cells {
+			for _, second := range cells[i+1:] {
+				actions = append(actions, Action{Type: ActionMarkCell, PlayerID: playerID, X: first[0], Y: first[1], X2: second[0], Y2: second[1]})
+			}
+		}
+		return actions
+	}
+
+	for _, cell := range cells {
+		x, y, z := boardCoords(game.Rules, cell)
+		action := Action{Type: ActionMarkCell, PlayerID: playerID, X: x, Y: y, Z: z}
+		if game.Rules.Variant != models.VariantOrderChaos {
+			actions = append(actions, action)
+			continue
+		}
+		for _, symbol := range orderChaosSymbols {
+			action.Symbol = symbol
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
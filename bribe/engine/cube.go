@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// 3次元の盤面で、層をまたぐ列の方向
+const (
+	DirectionPillar        = "pillar"        // 同じ位置を層方向に貫く列
+	DirectionCrossDiagonal = "crossDiagonal" // 行または列と層の方向に斜めに進む列
+	DirectionSpaceDiagonal = "spaceDiagonal" // 行・列・層の全ての方向に斜めに進む列
+)
+
+// cubeDirections は3次元の盤面で列を調べる13方向。逆向きは同じ列になるため含まない
+var cubeDirections = func() [][3]int {
+	var directions [][3]int
+	for dz := 0; dz <= 1; dz++ {
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				// 層が変わらない方向は縦横の盤面と同じ4方向に限る
+				if dz == 0 && (dx < 0 || (dx == 0 && dy <= 0)) {
+					continue
+				}
+				directions = append(directions, [3]int{dx, dy, dz})
+			}
+		}
+	}
+	return directions
+}()
+
+// cubeVariant はBoardDepth層の盤面を重ねた3次元の三目並べ。
+// 盤面は層ごとの行を順に並べたBoardで表し、層zの(x, y)はBoard[z*BoardHeight+x][y]に対応する
+type cubeVariant struct{}
+
+func (cubeVariant) reset(game *models.Game) {
+	game.Board = NewBoard(models.GameRules{
+		BoardWidth:  game.Rules.BoardWidth,
+		BoardHeight: game.Rules.BoardHeight * game.Rules.BoardDepth,
+	})
+}
+
+func (cubeVariant) locate(game *models.Game, action Action) ([2]int, error) {
+	if !inCube(game.Rules, action.X, action.Y, action.Z) {
+		return [2]int{}, ErrInvalidCell
+	}
+	return boardCell(game.Rules, action.X, action.Y, action.Z), nil
+}
+
+func (cubeVariant) checkMove(game *models.Game, playerID uint, x, y int) error {
+	return nil
+}
+
+func (cubeVariant) candidates(game *models.Game, playerID uint) [][2]int {
+	return getEmptyCellsExcept(game.Board, -1, -1)
+}
+
+func (v cubeVariant) alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int {
+	return anyOtherCell(v, game, playerID, selected)
+}
+
+func (cubeVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	x, y, z := boardCoords(game.Rules, cell)
+	for _, d := range cubeDirections {
+		line := cubeLineAt(game, x, y, z, d, symbol)
+		if len(line) == game.Rules.WinLength || (!game.Rules.ExactLength && len(line) > game.Rules.WinLength) {
+			winLine := &models.WinLine{Direction: cubeDirectionName(d)}
+			for _, c := range line {
+				winLine.Cells = append(winLine.Cells, [2]int{c[0], c[1]})
+				winLine.Layers = append(winLine.Layers, c[2])
+			}
+			return playerID, winLine, true
+		}
+	}
+	// ボードが全て埋まっているが、勝者がいない場合（引き分け）
+	return 0, nil, isBoardFull(game.Board)
+}
+
+// 層zの(x, y)を含み、方向dに同じ印が途切れずに続く列のセル（[x, y, z]）を端から順に返すヘルパー関数
+func cubeLineAt(game *models.Game, x, y, z int, d [3]int, symbol string) [][3]int {
+	matches := func(x, y, z int) bool {
+		return inCube(game.Rules, x, y, z) && game.Board[z*game.Rules.BoardHeight+x][y] == symbol
+	}
+
+	start := [3]int{x, y, z}
+	for matches(start[0]-d[0], start[1]-d[1], start[2]-d[2]) {
+		start = [3]int{start[0] - d[0], start[1] - d[1], start[2] - d[2]}
+	}
+	var line [][3]int
+	for c := start; matches(c[0], c[1], c[2]); c = [3]int{c[0] + d[0], c[1] + d[1], c[2] + d[2]} {
+		line = append(line, c)
+	}
+	return line
+}
+
+// 3次元の盤面の範囲内かどうかを返すヘルパー関数
+func inCube(rules models.GameRules, x, y, z int) bool {
+	return x >= 0 && y >= 0 && z >= 0 && x < rules.BoardHeight && y < rules.BoardWidth && z < rules.BoardDepth
+}
+
+// 列の方向の名前を返すヘルパー関数
+func cubeDirectionName(d [3]int) string {
+	if d[2] == 0 {
+		for _, planar := range lineDirections {
+			if planar.dx == d[0] && planar.dy == d[1] {
+				return planar.name
+			}
+		}
+	}
+	switch {
+	case d[0] == 0 && d[1] == 0:
+		return DirectionPillar
+	case d[0] == 0 || d[1] == 0:
+		return DirectionCrossDiagonal
+	default:
+		return DirectionSpaceDiagonal
+	}
+}
+
+// 盤面の座標をプレイヤーから見た行・列・層に変換するヘルパー関数。縦横の盤面では層は常に0
+func boardCoords(rules models.GameRules, cell [2]int) (int, int, int) {
+	if rules.Variant != models.VariantCube {
+		return cell[0], cell[1], 0
+	}
+	return cell[0] % rules.BoardHeight, cell[1], cell[0] / rules.BoardHeight
+}
+
+// プレイヤーから見た行・列・層を盤面の座標に変換するヘルパー関数。boardCoordsの逆変換
+func boardCell(rules models.GameRules, x, y, z int) [2]int {
+	if rules.Variant != models.VariantCube {
+		return [2]int{x, y}
+	}
+	return [2]int{z*rules.BoardHeight + x, y}
+}
+
+// BoardLayers は3次元の盤面を層ごとの盤面に分けて返す。3次元でない場合はnil
+func BoardLayers(rules models.GameRules, board [][]string) [][][]string {
+	if rules.Variant != models.VariantCube {
+		return nil
+	}
+	layers := make([][][]string, rules.BoardDepth)
+	for z := range layers {
+		layers[z] = board[z*rules.BoardHeight : (z+1)*rules.BoardHeight]
+	}
+	return layers
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestCubeLineWindows(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		// 各層の行・列・斜め、層をまたぐ柱と斜め、4本の空間対角線
+		{name: "3x3x3", size: 3, want: 49},
+		{name: "4x4x4", size: 4, want: 76},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.GameRules{Variant: models.VariantCube, BoardWidth: tt.size, BoardHeight: tt.size, BoardDepth: tt.size, WinLength: tt.size}
+			windows := LineWindows(rules)
+			if len(windows) != tt.want {
+				t.Errorf("len(LineWindows) = %d, want %d", len(windows), tt.want)
+			}
+			seen := make(map[[2]int]bool)
+			for _, window := range windows {
+				for _, cell := range window {
+					seen[cell] = true
+				}
+			}
+			if len(seen) != tt.size*tt.size*tt.size {
+				t.Errorf("LineWindows covers %d cells, want %d", len(seen), tt.size*tt.size*tt.size)
+			}
+		})
+	}
+}
+
+func TestCubeJudge(t *testing.T) {
+	tests := []struct {
+		name          string
+		marks         [][3]int // Xを置くセル（[x, y, z]）。最後のセルで判定する
+		wantWin       bool
+		wantDirection string
+		wantCells     [][2]int
+		wantLayers    []int
+	}{
+		{
+			name:          "row within a layer",
+			marks:         [][3]int{{1, 0, 2}, {1, 1, 2}, {1, 2, 2}},
+			wantWin:       true,
+			wantDirection: DirectionRow,
+			wantCells:     [][2]int{{1, 0}, {1, 1}, {1, 2}},
+			wantLayers:    []int{2, 2, 2},
+		},
+		{
+			name:          "pillar across the layers",
+			marks:         [][3]int{{2, 1, 0}, {2, 1, 2}, {2, 1, 1}},
+			wantWin:       true,
+			wantDirection: DirectionPillar,
+			wantCells:     [][2]int{{2, 1}, {2, 1}, {2, 1}},
+			wantLayers:    []int{0, 1, 2},
+		},
+		{
+			name:          "diagonal across the layers",
+			marks:         [][3]int{{0, 2, 0}, {1, 2, 1}, {2, 2, 2}},
+			wantWin:       true,
+			wantDirection: DirectionCrossDiagonal,
+			wantCells:     [][2]int{{0, 2}, {1, 2}, {2, 2}},
+			wantLayers:    []int{0, 1, 2},
+		},
+		{
+			name:          "space diagonal",
+			marks:         [][3]int{{0, 2, 2}, {2, 0, 0}, {1, 1, 1}},
+			wantWin:       true,
+			wantDirection: DirectionSpaceDiagonal,
+			wantCells:     [][2]int{{2, 0}, {1, 1}, {0, 2}},
+			wantLayers:    []int{0, 1, 2},
+		},
+		{
+			name:  "bent line is not a win",
+			marks: [][3]int{{0, 0, 0}, {1, 1, 1}, {2, 2, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.GameRules{Variant: models.VariantCube, BoardWidth: 3, BoardHeight: 3, BoardDepth: 3, WinLength: 3}
+			game := &models.Game{Rules: rules}
+			cubeVariant{}.reset(game)
+			for _, m := range tt.marks {
+				cell := boardCell(rules, m[0], m[1], m[2])
+				game.Board[cell[0]][cell[1]] = "X"
+			}
+			last := tt.marks[len(tt.marks)-1]
+
+			winner, winLine, finished := cubeVariant{}.judge(game, boardCell(rules, last[0], last[1], last[2]), testX, "X")
+			if !tt.wantWin {
+				if winner != 0 || winLine != nil || finished {
+					t.Fatalf("judge = (%d, %+v, %v), want no win", winner, winLine, finished)
+				}
+				return
+			}
+			if winner != testX || winLine == nil || !finished {
+				t.Fatalf("judge = (%d, %+v, %v), want a win for %d", winner, winLine, finished, testX)
+			}
+			if winLine.Direction != tt.wantDirection {
+				t.Errorf("Direction = %q, want %q", winLine.Direction, tt.wantDirection)
+			}
+			if !reflect.DeepEqual(winLine.Cells, tt.wantCells) {
+				t.Errorf("Cells = %v, want %v", winLine.Cells, tt.wantCells)
+			}
+			if !reflect.DeepEqual(winLine.Layers, tt.wantLayers) {
+				t.Errorf("Layers = %v, want %v", winLine.Layers, tt.wantLayers)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"time"
+
+	"xicserver/models"
+)
+
+// アクションの種類（クライアントから届く"actionType"と対応）
+const (
+	ActionMarkCell     = "markCell"
+	ActionBribe        = "bribe"
+	ActionAccuse       = "accuse"
+	ActionRetry        = "retry"
+	ActionTimeout      = "timeout" // サーバーが手番の制限時間切れを検知した場合に適用する
+	ActionResign       = "resign"
+	ActionOfferDraw    = "offerDraw"
+	ActionRespondDraw  = "respondDraw"
+	ActionUndoRequest  = "undoRequest"
+	ActionUndoResponse = "undoResponse"
+	ActionPowerUp      = "powerUp"
+	ActionCollapse     = "collapse"
+)
+
+// イベントの種類。エンジンはI/Oを行わず、呼び出し側がイベントに応じて送信や永続化を行う
+const (
+	EventSystemMessage   = "systemMessage"   // システムメッセージ。Toが0なら全プレイヤー宛て
+	EventGameState       = "gameState"       // ゲーム状態のブロードキャスト
+	EventResults         = "results"         // ラウンドまたはゲームの結果のブロードキャスト
+	EventGameFinished    = "gameFinished"    // ゲーム全体の終了。GameRoomとユーザーフラグの更新が必要
+	EventRetryRequested  = "retryRequested"  // Toのプレイヤーに再戦リクエストを通知
+	EventWaitingForMoves = "waitingForMoves" // 同時手番で他のプレイヤーの提出待ちであることのブロードキャスト
+)
+
+// アクションが受け付けられなかった場合のエラー。メッセージはそのままクライアントに送信される
+var (
+	ErrUnknownAction      = errors.New("Unknown action type")
+	ErrPlayerNotFound     = errors.New("Player not found in the game")
+	ErrInvalidCell        = errors.New("Invalid cell coordinates")
+	ErrCellMarked         = errors.New("Cell is already marked")
+	ErrCellBlocked        = errors.New("Cell is blocked")
+	ErrNotYourTurn        = errors.New("Not your turn")
+	ErrRoundNotInProgress = errors.New("Round is not in progress")
+	ErrRetryNotApplicable = errors.New("Retry request is not applicable")
+	ErrTimerNotExpired    = errors.New("Turn timer has not expired")
+	ErrMatchNotInProgress = errors.New("Match is not in progress")
+	ErrInvalidScope       = errors.New("Invalid scope")
+	ErrDrawOfferPending   = errors.New("A draw offer is already pending")
+	ErrNoDrawOffer        = errors.New("No draw offer to respond to")
+	ErrUndoDisabled       = errors.New("Takebacks are disabled in this room")
+	ErrNothingToUndo      = errors.New("No move of yours to take back")
+	ErrUndoRequestPending = errors.New("A takeback request is already pending")
+	ErrNoUndoRequest      = errors.New("No takeback request to respond to")
+	ErrBribesDisabled     = errors.New("Bribes and accusations are disabled in this room")
+	ErrAlreadyResigned    = errors.New("You have already resigned this round")
+	ErrForbiddenMove      = errors.New("Forbidden move under renju rules")
+	ErrWrongSubBoard      = errors.New("You must play in the targeted sub-board")
+	ErrColumnFull         = errors.New("Column is full")
+	ErrInvalidSymbol      = errors.New("Invalid symbol")
+	ErrNoObstacleLayout   = errors.New("No obstacle layout leaves a winnable line")
+	ErrPowerUpsDisabled   = errors.New("Power-ups are disabled in this room")
+	ErrNoPowerUp          = errors.New("You have no such power-up left")
+	ErrInvalidTarget      = errors.New("Invalid power-up target")
+	ErrCellShielded       = errors.New("Cell is shielded")
+	ErrMoveCommitted      = errors.New("You have already submitted a move this turn")
+	ErrCollapsePending    = errors.New("The entanglement must be collapsed first")
+	ErrNoCollapse         = errors.New("No entanglement to collapse")
+)
+
+// Action はプレイヤーがゲームに対して行う操作
+type Action struct {
+	Type      string
+	PlayerID  uint
+	X         int       // markCell, collapse: 行
+	Y         int       // markCell, collapse: 列（重力ルールでは列だけを指定する）
+	Z         int       // markCell: 層（3次元の盤面のみ）
+	Symbol    string    // markCell: 置く印（OrderとChaosのみ）
+	PowerUp   string    // powerUp: 使うパワーアップの種類
+	X2        int       // powerUp: 入れ替えるもう1つのセルの行。markCell: 量子三目並べで印を置くもう1つのセルの行
+	Y2        int       // powerUp: 入れ替えるもう1つのセルの列。markCell: 量子三目並べで印を置くもう1つのセルの列
+	WantRetry bool      // retry: 再戦を希望するかどうか
+	Scope     string    // resign, offerDraw: ScopeRound または ScopeMatch
+	Accept    bool      // respondDraw, undoResponse: 提案を受諾するかどうか
+	At        time.Time // アクションが適用される時刻。制限時間の計算に使用
+}
+
+// Event はアクションの結果として呼び出し側が処理すべき出来事
+type Event struct {
+	Type    string
+	To      uint // 宛先のプレイヤーID。0は全プレイヤー
+	Message string
+}
+
+// Apply はゲームの状態にアクションを適用し、新しい状態と発生したイベントを返す。
+// 渡されたgameは変更せず、乱数はすべてrandGenから取得するため、同じシードなら結果は再現できる。
+func Apply(game *models.Game, action Action, randGen *rand.Rand) (*models.Game, []Event, error) {
+	next := cloneGame(game)
+
+	var events []Event
+	var err error
+	switch action.Type {
+	case ActionMarkCell:
+		events, err = applyMarkCell(next, action, randGen)
+	case ActionBribe:
+		events, err = applyBribe(next, action)
+	case ActionAccuse:
+		events, err = applyAccuse(next, action, randGen)
+	case ActionRetry:
+		events, err = applyRetry(next, action, randGen)
+	case ActionTimeout:
+		events, err = applyTimeout(next, action, randGen)
+	case ActionResign:
+		events, err = applyResign(next, action, randGen)
+	case ActionOfferDraw:
+		events, err = applyOfferDraw(next, action)
+	case ActionRespondDraw:
+		events, err = applyRespondDraw(next, action)
+	case ActionUndoRequest:
+		events, err = applyUndoRequest(next, action)
+	case ActionUndoResponse:
+		events, err = applyUndoResponse(next, action)
+	case ActionPowerUp:
+		events, err = applyPowerUp(next, action, randGen)
+	case ActionCollapse:
+		events, err = applyCollapse(next, action, randGen)
+	default:
+		err = ErrUnknownAction
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return next, events, nil
+}
+
+// Snapshot はゲームの状態の複製を返します。
+// ロックを解放した後もボットの探索などで読めるように、参加処理で書き換わるプレイヤーの枠とオンライン状態も複製します。
+func Snapshot(game *models.Game) *models.Game {
+	snapshot := cloneGame(game)
+	snapshot.Players = append([]*models.Player(nil), game.Players...)
+	if game.PlayersOnlineStatus != nil {
+		snapshot.PlayersOnlineStatus = make(map[uint]bool, len(game.PlayersOnlineStatus))
+		for id, online := range game.PlayersOnlineStatus {
+			snapshot.PlayersOnlineStatus[id] = online
+		}
+	}
+	return snapshot
+}
+
+// エンジンが書き換えるフィールドをコピーしたゲームの複製を返すヘルパー関数
+func cloneGame(game *models.Game) *models.Game {
+	next := *game
+
+	next.Board = copyBoard(game.Board)
+	next.BribeCounts = append([]int(nil), game.BribeCounts...)
+	next.History = append([]models.MoveSnapshot(nil), game.History...)
+	next.Winners = append([]uint(nil), game.Winners...)
+	next.WinningLines = append([]*models.WinLine(nil), game.WinningLines...)
+	next.Ultimate = copyUltimate(game.Ultimate)
+	next.Quantum = copyQuantum(game.Quantum)
+	next.Hands = copyHands(game.Hands)
+	next.Shielded = append([][2]int(nil), game.Shielded...)
+	next.LastMoves = append([]models.Move(nil), game.LastMoves...)
+	next.RoundResigned = append([]uint(nil), game.RoundResigned...)
+	if game.PendingMoves != nil {
+		next.PendingMoves = make(map[uint][2]int, len(game.PendingMoves))
+		for id, cell := range game.PendingMoves {
+			next.PendingMoves[id] = cell
+		}
+	}
+	next.Revealed = copyRevealed(game.Revealed)
+
+	if game.DrawOffer != nil {
+		offer := *game.DrawOffer
+		offer.AcceptedBy = append([]uint(nil), game.DrawOffer.AcceptedBy...)
+		next.DrawOffer = &offer
+	}
+
+	if game.RetryRequests != nil {
+		next.RetryRequests = make(map[uint]bool, len(game.RetryRequests))
+		for id, wantRetry := range game.RetryRequests {
+			next.RetryRequests[id] = wantRetry
+		}
+	}
+
+	next.Clocks = copyClocks(game.Clocks)
+
+	return &next
+}
+
+// 霧のルールで公開されたセルを複製するヘルパー関数
+func copyRevealed(revealed [][]bool) [][]bool {
+	if revealed == nil {
+		return nil
+	}
+	copied := make([][]bool, len(revealed))
+	for i, row := range revealed {
+		copied[i] = append([]bool(nil), row...)
+	}
+	return copied
+}
+
+// 持ち時間を複製するヘルパー関数
+func copyClocks(clocks map[uint]time.Duration) map[uint]time.Duration {
+	if clocks == nil {
+		return nil
+	}
+	copied := make(map[uint]time.Duration, len(clocks))
+	for id, remaining := range clocks {
+		copied[id] = remaining
+	}
+	return copied
+}
+
+// プレイヤーIDからPlayersのインデックスを返すヘルパー関数。見つからない場合は-1
+func playerIndex(game *models.Game, playerID uint) int {
+	for i, player := range game.Players {
+		if player != nil && player.ID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// プレイヤーIDからそのプレイヤーのシンボルを返すヘルパー関数。見つからない場合は空文字列
+func playerSymbol(game *models.Game, playerID uint) string {
+	if i := playerIndex(game, playerID); i != -1 {
+		return game.Players[i].Symbol
+	}
+	return ""
+}
+
+// 対戦相手のプレイヤーIDを返すヘルパー関数。2人対戦専用のルールで使う
+func opponentID(game *models.Game, playerID uint) uint {
+	for _, player := range game.Players {
+		if player != nil && player.ID != playerID {
+			return player.ID
+		}
+	}
+	return 0
+}
+
+// Playersの並び順で次の手番となるプレイヤーのIDを返すヘルパー関数。ラウンドを投了したプレイヤーは飛ばし、見つからない場合は0。
+// チーム戦では席順に両チームのプレイヤーが交互に並ぶため、チームが交互に手番を持つ
+func nextPlayerID(game *models.Game, playerID uint) uint {
+	start := playerIndex(game, playerID)
+	for i := 1; i <= len(game.Players); i++ {
+		if player := game.Players[(start+i+len(game.Players))%len(game.Players)]; player != nil && player.ID != playerID && !slices.Contains(game.RoundResigned, player.ID) {
+			return player.ID
+		}
+	}
+	return 0
+}
+
+// PlayerSymbols は参加順に各プレイヤーへ割り当てるシンボル
+var PlayerSymbols = []string{"X", "O", "Δ"}
+
+// PlayerCount はルールで決められた対戦の人数を返す
+func PlayerCount(rules models.GameRules) int {
+	if rules.Teams {
+		return TeamPlayers
+	}
+	return max(rules.Players, 2)
+}
+
+// 投了や時間切れで負けたプレイヤー以外の中から勝者を決めるヘルパー関数。
+// 2人対戦では対戦相手、チーム戦では相手チーム、
+// 3人以上ではラウンド勝利数が単独で最も多いプレイヤーで、並んでいる場合は0（引き分け）
+func beneficiaryOf(game *models.Game, loserID uint) uint {
+	if PlayerCount(game.Rules) == 2 {
+		return opponentID(game, loserID)
+	}
+	if game.Rules.Teams {
+		return teamLeader(game, nextPlayerID(game, loserID))
+	}
+	wins := countRoundWins(game)
+	var leader uint
+	best, tied := -1, false
+	for _, player := range game.Players {
+		if player == nil || player.ID == loserID {
+			continue
+		}
+		if w := wins[player.ID]; w > best {
+			leader, best, tied = player.ID, w, false
+		} else if w == best {
+			tied = true
+		}
+	}
+	if tied {
+		return 0
+	}
+	return leader
+}
+
+// 全プレイヤー宛てのシステムメッセージイベントを作るヘルパー関数
+func messageAll(message string) Event {
+	return Event{Type: EventSystemMessage, Message: message}
+}
+
+// 特定のプレイヤー宛てのシステムメッセージイベントを作るヘルパー関数
+func messageTo(playerID uint, message string) Event {
+	return Event{Type: EventSystemMessage, To: playerID, Message: message}
+}
@@ -0,0 +1,436 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+// テストで使うプレイヤーのID
+const (
+	testX uint = 1
+	testO uint = 2
+)
+
+// テストの開始時刻
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// 審判に不正がある3x3の三目並べのルール。審判が中立なら常に選択どおりに印を置く
+func testRules() models.GameRules {
+	return models.GameRules{
+		BoardWidth:   3,
+		BoardHeight:  3,
+		WinLength:    3,
+		MatchFormat:  models.MatchBestOf,
+		Rounds:       3,
+		TargetWins:   2,
+		Bias:         "biased",
+		MarkAccuracy: 1,
+	}
+}
+
+// XとOの2人が参加し、Xの手番で始まるゲームを作成するヘルパー関数
+func newTestGame(t *testing.T, rules models.GameRules) *models.Game {
+	t.Helper()
+	game := &models.Game{
+		ID:            1,
+		Players:       make([]*models.Player, PlayerCount(rules)),
+		Status:        StatusInProgress,
+		Round:         1,
+		Rules:         rules,
+		RefereeStatus: "normal_01",
+		BribeCounts:   make([]int, PlayerCount(rules)),
+	}
+	ResetBoard(game, rand.New(rand.NewSource(1)))
+	for seat := range game.Players {
+		game.Players[seat] = &models.Player{ID: uint(seat + 1), Symbol: SeatSymbol(rules, seat)}
+	}
+	StartMatch(game, testX, testStart)
+	return game
+}
+
+// アクションを順に適用し、最後の状態とイベントを返すヘルパー関数。途中でエラーになった場合はテストを失敗させる
+func applyAll(t *testing.T, game *models.Game, randGen *rand.Rand, actions ...Action) (*models.Game, []Event) {
+	t.Helper()
+	var events []Event
+	for _, action := range actions {
+		next, applied, err := Apply(game, action, randGen)
+		if err != nil {
+			t.Fatalf("Apply(%+v) returned error: %v", action, err)
+		}
+		game, events = next, applied
+	}
+	return game, events
+}
+
+// 印を置くアクションを返すヘルパー関数
+func mark(playerID uint, x, y int) Action {
+	return Action{Type: ActionMarkCell, PlayerID: playerID, X: x, Y: y, At: testStart}
+}
+
+// 盤面に置かれた印の数を返すヘルパー関数
+func countMarks(board [][]string) int {
+	count := 0
+	for _, row := range board {
+		for _, cell := range row {
+			if cell != "" {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestApplyMarkCell(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   []Action
+		action  Action
+		wantErr error
+	}{
+		{name: "places the mark", action: mark(testX, 1, 1)},
+		{name: "rejects the other player", action: mark(testO, 1, 1), wantErr: ErrNotYourTurn},
+		{name: "rejects a marked cell", setup: []Action{mark(testX, 1, 1)}, action: mark(testO, 1, 1), wantErr: ErrCellMarked},
+		{name: "rejects a cell outside the board", action: mark(testX, 3, 0), wantErr: ErrInvalidCell},
+		{name: "rejects an unknown player", action: mark(9, 0, 0), wantErr: ErrNotYourTurn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, testRules()), randGen, tt.setup...)
+			next, events, err := Apply(game, tt.action, randGen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := next.Board[tt.action.X][tt.action.Y]; got != "X" {
+				t.Errorf("Board[%d][%d] = %q, want %q", tt.action.X, tt.action.Y, got, "X")
+			}
+			if next.CurrentTurn != testO {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, testO)
+			}
+			if len(events) == 0 || events[len(events)-1].Type != EventGameState {
+				t.Errorf("events = %+v, want a trailing %s event", events, EventGameState)
+			}
+		})
+	}
+}
+
+func TestApplyMarkCellWinsRound(t *testing.T) {
+	randGen := rand.New(rand.NewSource(1))
+	game, events := applyAll(t, newTestGame(t, testRules()), randGen,
+		mark(testX, 0, 0), mark(testO, 1, 0),
+		mark(testX, 0, 1), mark(testO, 1, 1),
+		mark(testX, 0, 2),
+	)
+	if game.Status != StatusRoundFinished {
+		t.Fatalf("Status = %q, want %q", game.Status, StatusRoundFinished)
+	}
+	if !reflect.DeepEqual(game.Winners, []uint{testX}) {
+		t.Errorf("Winners = %v, want [%d]", game.Winners, testX)
+	}
+	if line := game.WinningLines[0]; line == nil || line.Direction != DirectionRow || line.MoveNumber != 5 {
+		t.Errorf("WinningLines[0] = %+v, want a row completed on move 5", line)
+	}
+	if len(events) == 0 || events[0].Type != EventResults {
+		t.Errorf("events = %+v, want %s first", events, EventResults)
+	}
+}
+
+func TestApplyMarkCellReferee(t *testing.T) {
+	tests := []struct {
+		name       string
+		accuracy   float64
+		degree     int
+		favored    uint
+		wantMoved  bool
+		seedsToTry int64
+	}{
+		{name: "neutral referee with perfect accuracy", accuracy: 1, seedsToTry: 20},
+		{name: "neutral referee that always misplaces", accuracy: 0, wantMoved: true, seedsToTry: 20},
+		{name: "referee favoring the player", accuracy: 0, degree: 1, favored: testX, seedsToTry: 20},
+		{name: "referee favoring the opponent", accuracy: 1, degree: 1, favored: testO, wantMoved: true, seedsToTry: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= tt.seedsToTry; seed++ {
+				rules := testRules()
+				rules.MarkAccuracy = tt.accuracy
+				game := newTestGame(t, rules)
+				game.BiasDegree, game.FavoredPlayer = tt.degree, tt.favored
+
+				next, _, err := Apply(game, mark(testX, 1, 1), rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatalf("seed %d: Apply() error = %v", seed, err)
+				}
+				if countMarks(next.Board) != 1 {
+					t.Fatalf("seed %d: board has %d marks, want 1", seed, countMarks(next.Board))
+				}
+				if moved := next.Board[1][1] == ""; moved != tt.wantMoved {
+					t.Fatalf("seed %d: mark moved = %v, want %v (board %v)", seed, moved, tt.wantMoved, next.Board)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyBribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		limit       int
+		bribes      []int
+		degree      int
+		favored     uint
+		wantDegree  int
+		wantFavored uint
+		wantBribes  []int
+		wantMessage string
+	}{
+		{name: "neutral referee leans to the briber", status: "normal_01", bribes: []int{0, 0}, wantDegree: 1, wantFavored: testX, wantBribes: []int{1, 0}, wantMessage: "REFEREE: Your Bribe accepted!"},
+		{name: "counter bribe makes the referee neutral", status: "normal_01", bribes: []int{0, 1}, degree: 1, favored: testO, wantBribes: []int{1, 1}, wantMessage: "REFEREE: Your Bribe accepted!"},
+		{name: "second bribe does not exceed degree one", status: "normal_01", bribes: []int{1, 0}, degree: 1, favored: testX, wantDegree: 1, wantFavored: testX, wantBribes: []int{2, 0}, wantMessage: "REFEREE: Your Bribe accepted!"},
+		{name: "bribe limit reached", status: "normal_01", limit: 1, bribes: []int{1, 0}, wantBribes: []int{1, 0}, wantMessage: "SYSTEM: Bribe ignored, you have reached the bribe limit"},
+		{name: "angry referee ignores bribes", status: "angry_01", bribes: []int{0, 0}, wantBribes: []int{0, 0}, wantMessage: "SYSTEM: Bribe ignored, referee status is not normal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.BribeLimit = tt.limit
+			game := newTestGame(t, rules)
+			game.RefereeStatus = tt.status
+			game.BribeCounts = tt.bribes
+			game.BiasDegree, game.FavoredPlayer = tt.degree, tt.favored
+
+			next, events, err := Apply(game, Action{Type: ActionBribe, PlayerID: testX}, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if next.BiasDegree != tt.wantDegree || next.FavoredPlayer != tt.wantFavored {
+				t.Errorf("bias = (%d, %d), want (%d, %d)", next.BiasDegree, next.FavoredPlayer, tt.wantDegree, tt.wantFavored)
+			}
+			if !reflect.DeepEqual(next.BribeCounts, tt.wantBribes) {
+				t.Errorf("BribeCounts = %v, want %v", next.BribeCounts, tt.wantBribes)
+			}
+			if events[0].To != testX || events[0].Message != tt.wantMessage {
+				t.Errorf("events[0] = %+v, want message %q to the briber", events[0], tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestApplyAccuse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		degree      int
+		favored     uint
+		wantStatus  string
+		wantFavored uint
+		wantCount   uint
+	}{
+		{name: "wrong accusation of a neutral referee", status: "normal_01", wantStatus: "angry", wantFavored: testO, wantCount: 4},
+		{name: "accusation of a bribed referee", status: "normal_01", degree: 1, favored: testO, wantStatus: "sad", wantFavored: testX, wantCount: 4},
+		{name: "accusing the referee you bribed", status: "normal_01", degree: 1, favored: testX, wantStatus: "angry", wantFavored: testO, wantCount: 4},
+		{name: "accusation is ineffective while the referee is fixed", status: "sad_01", degree: 1, favored: testO, wantStatus: "sad_01", wantFavored: testO, wantCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestGame(t, testRules())
+			game.RefereeStatus = tt.status
+			game.BiasDegree, game.FavoredPlayer = tt.degree, tt.favored
+			if !strings.HasPrefix(tt.status, "normal") {
+				game.RefereeCount = 2
+			}
+
+			next, _, err := Apply(game, Action{Type: ActionAccuse, PlayerID: testX}, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !strings.HasPrefix(next.RefereeStatus, tt.wantStatus) {
+				t.Errorf("RefereeStatus = %q, want prefix %q", next.RefereeStatus, tt.wantStatus)
+			}
+			if next.FavoredPlayer != tt.wantFavored || next.BiasDegree != 1 {
+				t.Errorf("bias = (%d, %d), want (1, %d)", next.BiasDegree, next.FavoredPlayer, tt.wantFavored)
+			}
+			if next.RefereeCount != tt.wantCount {
+				t.Errorf("RefereeCount = %d, want %d", next.RefereeCount, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRefereeCountdownRestoresNormalStatus(t *testing.T) {
+	randGen := rand.New(rand.NewSource(1))
+	game, _ := applyAll(t, newTestGame(t, testRules()), randGen, Action{Type: ActionAccuse, PlayerID: testX})
+	// 審判に不利に扱われるXの印はずれるため、空いている最初のセルを選び続ける
+	for moves := 1; moves <= 4; moves++ {
+		if moves == 4 && (game.RefereeCount != 1 || strings.HasPrefix(game.RefereeStatus, "normal")) {
+			t.Fatalf("after 3 moves: RefereeCount = %d, RefereeStatus = %q, want 1 and a fixed status", game.RefereeCount, game.RefereeStatus)
+		}
+		cell := getEmptyCellsExcept(game.Board, -1, -1)[0]
+		game, _ = applyAll(t, game, randGen, mark(game.CurrentTurn, cell[0], cell[1]))
+	}
+	if game.RefereeCount != 0 || !strings.HasPrefix(game.RefereeStatus, "normal") {
+		t.Errorf("after 4 moves: RefereeCount = %d, RefereeStatus = %q, want 0 and a normal status", game.RefereeCount, game.RefereeStatus)
+	}
+	if game.BiasDegree != 1 || game.FavoredPlayer != testO {
+		t.Errorf("bias = (%d, %d), want the bias to outlast the fixed status", game.BiasDegree, game.FavoredPlayer)
+	}
+}
+
+func TestApplyRetry(t *testing.T) {
+	randGen := rand.New(rand.NewSource(1))
+	finished, _ := applyAll(t, newTestGame(t, testRules()), randGen,
+		mark(testX, 0, 0), mark(testO, 1, 0),
+		mark(testX, 0, 1), mark(testO, 1, 1),
+		mark(testX, 0, 2),
+	)
+	finished.BribeCounts = []int{1, 0}
+	finished.BiasDegree, finished.FavoredPlayer = 1, testX
+
+	t.Run("rejected while the round is in progress", func(t *testing.T) {
+		_, _, err := Apply(newTestGame(t, testRules()), Action{Type: ActionRetry, PlayerID: testX, WantRetry: true}, randGen)
+		if !errors.Is(err, ErrRetryNotApplicable) {
+			t.Errorf("Apply() error = %v, want %v", err, ErrRetryNotApplicable)
+		}
+	})
+
+	t.Run("first request notifies the opponent", func(t *testing.T) {
+		next, events, err := Apply(finished, Action{Type: ActionRetry, PlayerID: testX, WantRetry: true}, randGen)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if next.Status != StatusRoundFinished {
+			t.Errorf("Status = %q, want %q", next.Status, StatusRoundFinished)
+		}
+		if len(events) != 1 || events[0].Type != EventRetryRequested || events[0].To != testO {
+			t.Errorf("events = %+v, want a retry notification to %d", events, testO)
+		}
+	})
+
+	t.Run("both requests start the next round", func(t *testing.T) {
+		next, _ := applyAll(t, finished, randGen,
+			Action{Type: ActionRetry, PlayerID: testX, WantRetry: true},
+			Action{Type: ActionRetry, PlayerID: testO, WantRetry: true},
+		)
+		if next.Status != StatusInProgress || next.Round != 2 {
+			t.Fatalf("Status = %q, Round = %d, want %q and 2", next.Status, next.Round, StatusInProgress)
+		}
+		if countMarks(next.Board) != 0 || len(next.History) != 0 {
+			t.Errorf("board has %d marks and %d history entries, want a fresh round", countMarks(next.Board), len(next.History))
+		}
+		if next.BiasDegree != 0 || next.FavoredPlayer != 0 || !reflect.DeepEqual(next.BribeCounts, []int{0, 0}) {
+			t.Errorf("bias = (%d, %d), bribes = %v, want the referee reset", next.BiasDegree, next.FavoredPlayer, next.BribeCounts)
+		}
+		if !reflect.DeepEqual(next.Winners, []uint{testX}) {
+			t.Errorf("Winners = %v, want the first round kept", next.Winners)
+		}
+	})
+
+	t.Run("declining finishes the match", func(t *testing.T) {
+		next, events, err := Apply(finished, Action{Type: ActionRetry, PlayerID: testO, WantRetry: false}, randGen)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if next.Status != StatusFinished || next.MatchWinner != testX {
+			t.Errorf("Status = %q, MatchWinner = %d, want %q and %d", next.Status, next.MatchWinner, StatusFinished, testX)
+		}
+		if events[len(events)-1].Type != EventGameFinished {
+			t.Errorf("events = %+v, want a trailing %s event", events, EventGameFinished)
+		}
+	})
+}
+
+func TestApplyDoesNotMutateInput(t *testing.T) {
+	randGen := rand.New(rand.NewSource(1))
+	rules := testRules()
+	rules.TimeBank = 60
+	opening, _ := applyAll(t, newTestGame(t, rules), randGen, mark(testX, 0, 0), mark(testO, 1, 1))
+	base, _ := applyAll(t, opening, randGen, Action{Type: ActionBribe, PlayerID: testX})
+	finished, _ := applyAll(t, opening, randGen, mark(testX, 0, 1), mark(testO, 2, 2), mark(testX, 0, 2))
+
+	tests := []struct {
+		name   string
+		game   *models.Game
+		action Action
+	}{
+		{name: "mark", game: base, action: mark(testX, 2, 0)},
+		{name: "bribe", game: base, action: Action{Type: ActionBribe, PlayerID: testO}},
+		{name: "accuse", game: base, action: Action{Type: ActionAccuse, PlayerID: testO}},
+		{name: "retry", game: finished, action: Action{Type: ActionRetry, PlayerID: testX, WantRetry: true}},
+		{name: "rejected action", game: base, action: mark(testO, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := cloneGame(tt.game)
+			Apply(tt.game, tt.action, rand.New(rand.NewSource(1)))
+			if !reflect.DeepEqual(tt.game, before) {
+				t.Errorf("Apply(%s) modified the input game", tt.action.Type)
+			}
+		})
+	}
+}
+
+func TestBribeAndAccuseDisabledInFairRooms(t *testing.T) {
+	rules := testRules()
+	rules.Bias = models.BiasFair
+	game := newTestGame(t, rules)
+	for _, actionType := range []string{ActionBribe, ActionAccuse} {
+		if _, _, err := Apply(game, Action{Type: actionType, PlayerID: testX}, rand.New(rand.NewSource(1))); !errors.Is(err, ErrBribesDisabled) {
+			t.Errorf("Apply(%s) error = %v, want %v", actionType, err, ErrBribesDisabled)
+		}
+	}
+}
+
+func TestThreePlayerTurnOrder(t *testing.T) {
+	const testDelta uint = 3
+	timeout := func(seconds int) Action {
+		return Action{Type: ActionTimeout, At: testStart.Add(time.Duration(seconds) * time.Second)}
+	}
+	accuse := func(playerID uint) Action {
+		return Action{Type: ActionAccuse, PlayerID: playerID, At: testStart}
+	}
+	tests := []struct {
+		name        string
+		actions     []Action
+		wantTurn    uint
+		wantFavored uint
+	}{
+		{name: "first seat starts", wantTurn: testX},
+		{name: "second seat follows the first", actions: []Action{mark(testX, 0, 0)}, wantTurn: testO},
+		{name: "third seat follows the second", actions: []Action{mark(testX, 0, 0), mark(testO, 1, 1)}, wantTurn: testDelta},
+		{name: "turn returns to the first seat", actions: []Action{mark(testX, 0, 0), mark(testO, 1, 1), mark(testDelta, 2, 2)}, wantTurn: testX},
+		{name: "timeouts pass the turn around", actions: []Action{timeout(10), timeout(20), timeout(30)}, wantTurn: testX},
+		{name: "mark after a timeout", actions: []Action{timeout(10), mark(testO, 1, 1)}, wantTurn: testDelta},
+		// 外れた糾弾では、審判は糾弾したプレイヤーの次の席に傾く
+		{name: "wrong accusation favors the next seat", actions: []Action{accuse(testX)}, wantTurn: testX, wantFavored: testO},
+		{name: "wrong accusation by the last seat favors the first", actions: []Action{accuse(testDelta)}, wantTurn: testX, wantFavored: testX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.Players = 3
+			rules.TurnTimeLimit = 10
+			rules.TimeoutPenalty = models.TimeoutSkipTurn
+			game, _ := applyAll(t, newTestGame(t, rules), rand.New(rand.NewSource(1)), tt.actions...)
+
+			if game.Status != StatusInProgress {
+				t.Fatalf("Status = %q, want %q", game.Status, StatusInProgress)
+			}
+			if game.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", game.CurrentTurn, tt.wantTurn)
+			}
+			if game.FavoredPlayer != tt.wantFavored {
+				t.Errorf("FavoredPlayer = %d, want %d", game.FavoredPlayer, tt.wantFavored)
+			}
+		})
+	}
+}
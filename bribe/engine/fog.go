@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// 霧のルールで、印が置かれたセルを選んで失敗したプレイヤーにそのセルを公開する。
+// 手番はそのまま続き、プレイヤーは別のセルを選び直す
+func revealByFailedMark(game *models.Game, playerID uint, cell [2]int) []Event {
+	reveal(game, cell)
+	return []Event{
+		messageTo(playerID, "SYSTEM: That cell is already taken! The hidden mark has been revealed."),
+		{Type: EventGameState},
+	}
+}
+
+// 霧のルールで、プレイヤーが最後に置いた印を公開するヘルパー関数。まだ印を置いていない場合は何もしない
+func revealLastMarkOf(game *models.Game, playerID uint) {
+	if cell, ok := lastMarkOf(game, playerID); ok {
+		reveal(game, cell)
+	}
+}
+
+// セルを両方のプレイヤーに公開するヘルパー関数
+func reveal(game *models.Game, cell [2]int) {
+	if game.Revealed == nil {
+		game.Revealed = make([][]bool, len(game.Board))
+		for i, row := range game.Board {
+			game.Revealed[i] = make([]bool, len(row))
+		}
+	}
+	game.Revealed[cell[0]][cell[1]] = true
+}
+
+// 履歴からプレイヤーが現在のラウンドで最後に印を置いたセルを返すヘルパー関数。
+// 審判が別のセルに置いた場合も、実際に印が置かれたセルを返す
+func lastMarkOf(game *models.Game, playerID uint) ([2]int, bool) {
+	for i := len(game.History) - 1; i >= 0; i-- {
+		if teamLeader(game, game.History[i].PlayerID) != teamLeader(game, playerID) {
+			continue
+		}
+		after := game.Board
+		if i+1 < len(game.History) {
+			after = game.History[i+1].Board
+		}
+		for x, row := range game.History[i].Board {
+			for y, before := range row {
+				if before == "" && after[x][y] != "" {
+					return [2]int{x, y}, true
+				}
+			}
+		}
+	}
+	return [2]int{}, false
+}
+
+// プレイヤーからセルの印が見えるかどうかを返すヘルパー関数
+func isVisibleTo(game *models.Game, viewerID uint, cell [2]int) bool {
+	if !game.Rules.Fog || game.Status != StatusInProgress {
+		return true
+	}
+	mark := game.Board[cell[0]][cell[1]]
+	if mark == "" || mark == ObstacleMark || mark == playerSymbol(game, viewerID) {
+		return true
+	}
+	return game.Revealed != nil && game.Revealed[cell[0]][cell[1]]
+}
+
+// VisibleBoard はプレイヤーから見える盤面を返す。霧のルールでは、ラウンドの進行中は
+// 公開されていない相手の印を空のセルとして隠す。ラウンドが終わると全ての印が見える
+func VisibleBoard(game *models.Game, viewerID uint) [][]string {
+	if !game.Rules.Fog || game.Status != StatusInProgress {
+		return game.Board
+	}
+	board := copyBoard(game.Board)
+	for x, row := range board {
+		for y := range row {
+			if !isVisibleTo(game, viewerID, [2]int{x, y}) {
+				board[x][y] = ""
+			}
+		}
+	}
+	return board
+}
+
+// VisibleLastMove はプレイヤーから見える最後の手を返す。見えない相手の手の場合はnil
+func VisibleLastMove(game *models.Game, viewerID uint) *models.Move {
+	move := game.LastMove
+	if move == nil || move.PlayerID == viewerID || !game.Rules.Fog {
+		return move
+	}
+	if !isVisibleTo(game, viewerID, boardCell(game.Rules, move.X, move.Y, move.Z)) {
+		return nil
+	}
+	return move
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"xicserver/models"
+)
+
+// 霧のルールの3x3の三目並べのルール
+func fogRules() models.GameRules {
+	rules := testRules()
+	rules.Fog = true
+	return rules
+}
+
+func TestFogVisibility(t *testing.T) {
+	bribe := Action{Type: ActionBribe, PlayerID: testO, At: testStart}
+	accuse := Action{Type: ActionAccuse, PlayerID: testX, At: testStart}
+	tests := []struct {
+		name         string
+		actions      []Action
+		wantBoard    []string // Xから見える盤面
+		wantLastMove bool     // Xから相手の最後の手が見えるかどうか
+	}{
+		{
+			name:      "own mark is visible",
+			actions:   []Action{mark(testX, 0, 0)},
+			wantBoard: []string{"X..", "...", "..."},
+		},
+		{
+			name:      "opponent's mark and last move are hidden",
+			actions:   []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			wantBoard: []string{"X..", "...", "..."},
+		},
+		{
+			name:         "failed placement reveals the cell",
+			actions:      []Action{mark(testX, 0, 0), mark(testO, 1, 1), mark(testX, 1, 1)},
+			wantBoard:    []string{"X..", ".O.", "..."},
+			wantLastMove: true,
+		},
+		{
+			name:         "correct accusation reveals the briber's last mark",
+			actions:      []Action{mark(testX, 0, 0), mark(testO, 1, 1), bribe, accuse},
+			wantBoard:    []string{"X..", ".O.", "..."},
+			wantLastMove: true,
+		},
+		{
+			name:      "wrong accusation reveals nothing",
+			actions:   []Action{mark(testX, 0, 0), mark(testO, 1, 1), accuse},
+			wantBoard: []string{"X..", "...", "..."},
+		},
+		{
+			name:         "everything is revealed at round end",
+			actions:      []Action{mark(testX, 0, 0), mark(testO, 1, 0), mark(testX, 0, 1), mark(testO, 1, 1), mark(testX, 2, 2), mark(testO, 1, 2)},
+			wantBoard:    []string{"XX.", "OOO", "..X"},
+			wantLastMove: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _ := applyAll(t, newTestGame(t, fogRules()), rand.New(rand.NewSource(1)), tt.actions...)
+
+			if want := parseBoard(tt.wantBoard...); !reflect.DeepEqual(VisibleBoard(game, testX), want) {
+				t.Errorf("VisibleBoard(X) = %v, want %v", VisibleBoard(game, testX), want)
+			}
+			// 自分の最後の手は常に見える
+			if game.LastMove.PlayerID == testX {
+				if lastMove := VisibleLastMove(game, testX); lastMove != game.LastMove {
+					t.Errorf("VisibleLastMove(X) = %+v, want own move %+v", lastMove, game.LastMove)
+				}
+				return
+			}
+			if got := VisibleLastMove(game, testX) != nil; got != tt.wantLastMove {
+				t.Errorf("VisibleLastMove(X) visible = %v, want %v", got, tt.wantLastMove)
+			}
+		})
+	}
+}
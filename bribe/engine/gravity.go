@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// gravityVariant は列だけを選び、印がその列の一番下の空いたセルに落ちる重力ルール（四目並べ）。
+// 審判が不正をする場合は、印を隣の列に落とす。勝敗判定は標準ルールと同じ
+type gravityVariant struct {
+	standardVariant
+}
+
+func (gravityVariant) locate(game *models.Game, action Action) ([2]int, error) {
+	column := action.Y
+	if column < 0 || len(game.Board) == 0 || column >= len(game.Board[0]) {
+		return [2]int{}, ErrInvalidCell
+	}
+	row, ok := dropRow(game.Board, column)
+	if !ok {
+		return [2]int{}, ErrColumnFull
+	}
+	return [2]int{row, column}, nil
+}
+
+func (gravityVariant) candidates(game *models.Game, playerID uint) [][2]int {
+	var cells [][2]int
+	for column := range game.Board[0] {
+		if row, ok := dropRow(game.Board, column); ok {
+			cells = append(cells, [2]int{row, column})
+		}
+	}
+	return cells
+}
+
+func (gravityVariant) alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int {
+	var cells [][2]int
+	for _, column := range []int{selected[1] - 1, selected[1] + 1} {
+		if column < 0 || column >= len(game.Board[0]) {
+			continue
+		}
+		if row, ok := dropRow(game.Board, column); ok {
+			cells = append(cells, [2]int{row, column})
+		}
+	}
+	return cells
+}
+
+// 列に落とした印が止まる行を返すヘルパー関数。列が埋まっている場合はfalse
+func dropRow(board [][]string, column int) (int, bool) {
+	for row := len(board) - 1; row >= 0; row-- {
+		if board[row][column] == "" {
+			return row, true
+		}
+	}
+	return 0, false
+}
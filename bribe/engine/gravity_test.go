@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"xicserver/models"
+)
+
+// 幅4、高さ3の重力ルールのルール
+func gravityRules() models.GameRules {
+	rules := testRules()
+	rules.Variant = models.VariantGravity
+	rules.BoardWidth, rules.BoardHeight = 4, 3
+	return rules
+}
+
+func TestGravityDrop(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    []string
+	}{
+		{
+			name:    "falls to the bottom row",
+			actions: []Action{mark(testX, 0, 2)},
+			want:    []string{"....", "....", "..X."},
+		},
+		{
+			name:    "stacks on the previous mark",
+			actions: []Action{mark(testX, 2, 1), mark(testO, 2, 1), mark(testX, 0, 1)},
+			want:    []string{".X..", ".O..", ".X.."},
+		},
+		{
+			name:    "columns are independent",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 0, 3), mark(testX, 0, 3)},
+			want:    []string{"....", "...X", "X..O"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _ := applyAll(t, newTestGame(t, gravityRules()), rand.New(rand.NewSource(1)), tt.actions...)
+			if want := parseBoard(tt.want...); !reflect.DeepEqual(game.Board, want) {
+				t.Errorf("Board = %v, want %v", game.Board, want)
+			}
+		})
+	}
+}
+
+func TestGravityRejectsMoves(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		invalid Action
+		wantErr error
+	}{
+		{
+			name:    "full column",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 0, 0), mark(testX, 0, 0)},
+			invalid: mark(testO, 0, 0),
+			wantErr: ErrColumnFull,
+		},
+		{name: "column left of the board", invalid: mark(testX, 0, -1), wantErr: ErrInvalidCell},
+		{name: "column right of the board", invalid: mark(testX, 0, 4), wantErr: ErrInvalidCell},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, gravityRules()), randGen, tt.actions...)
+			if _, _, err := Apply(game, tt.invalid, randGen); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Apply error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGravityBiasedRefereeShiftsColumn(t *testing.T) {
+	tests := []struct {
+		name   string
+		board  []string
+		column int
+		want   [][2]int // 印が落ちうるセル
+	}{
+		{name: "left edge", board: []string{"....", "....", "...."}, column: 0, want: [][2]int{{2, 1}}},
+		{name: "right edge", board: []string{"....", "....", "...."}, column: 3, want: [][2]int{{2, 2}}},
+		{name: "either neighbour", board: []string{"....", "....", "...."}, column: 1, want: [][2]int{{2, 0}, {2, 2}}},
+		{name: "full neighbour is skipped", board: []string{"O...", "X...", "O..."}, column: 1, want: [][2]int{{2, 2}}},
+		{name: "stacks in the neighbour", board: []string{"....", "....", ".XO."}, column: 1, want: [][2]int{{1, 2}, {2, 0}}},
+		// 隣の列が全て埋まっている場合は、選択した列に落とす
+		{name: "no free neighbour", board: []string{".O..", ".X..", ".O.."}, column: 0, want: [][2]int{{2, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				game := newTestGame(t, gravityRules())
+				game.Board = parseBoard(tt.board...)
+				// 審判は相手に傾いているため、常に選択した列以外に落とす
+				game.BiasDegree, game.FavoredPlayer = 1, testO
+
+				next, _, err := Apply(game, mark(testX, 0, tt.column), rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatalf("Apply returned error: %v", err)
+				}
+				cell := [2]int{next.LastMove.X, next.LastMove.Y}
+				if !slices.Contains(tt.want, cell) || next.Board[cell[0]][cell[1]] != "X" {
+					t.Errorf("seed %d: mark landed at %v, want one of %v", seed, cell, tt.want)
+				}
+			}
+		})
+	}
+}
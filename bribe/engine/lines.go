@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// 勝利した列の方向
+const (
+	DirectionRow          = "row"          // 横
+	DirectionColumn       = "column"       // 縦
+	DirectionDiagonal     = "diagonal"     // 左上から右下
+	DirectionAntiDiagonal = "antiDiagonal" // 右上から左下
+)
+
+// 列を調べる4方向。dxは行、dyは列の増分
+var lineDirections = [...]struct {
+	dx, dy int
+	name   string
+}{
+	{0, 1, DirectionRow},
+	{1, 0, DirectionColumn},
+	{1, 1, DirectionDiagonal},
+	{1, -1, DirectionAntiDiagonal},
+}
+
+// セルが盤面の範囲内かどうかを返すヘルパー関数。盤面は長方形であることを前提とする
+func inBounds(board [][]string, x, y int) bool {
+	return x >= 0 && y >= 0 && x < len(board) && y < len(board[x])
+}
+
+// (x, y)から(dx, dy)の方向に、同じ印が途切れずに続くセルを順に返すヘルパー関数。(x, y)自身は含まない
+func walk(board [][]string, x, y, dx, dy int, symbol string) [][2]int {
+	var cells [][2]int
+	for x, y = x+dx, y+dy; inBounds(board, x, y) && board[x][y] == symbol; x, y = x+dx, y+dy {
+		cells = append(cells, [2]int{x, y})
+	}
+	return cells
+}
+
+// (x, y)を含み、(dx, dy)の方向に同じ印が途切れずに続く列のセルを端から順に返すヘルパー関数
+func lineAt(board [][]string, x, y, dx, dy int, symbol string) [][2]int {
+	backward := walk(board, x, y, -dx, -dy, symbol)
+	forward := walk(board, x, y, dx, dy, symbol)
+
+	cells := make([][2]int, 0, len(backward)+1+len(forward))
+	for i := len(backward) - 1; i >= 0; i-- {
+		cells = append(cells, backward[i])
+	}
+	cells = append(cells, [2]int{x, y})
+	return append(cells, forward...)
+}
+
+// checkWinAt は(x, y)に置かれた印を含み、symbolがwinLength個以上並んだ列があればそれを返す。
+// 直前の手で勝敗が決まったかを調べる場合はこちらを使い、盤面全体を走査しない。
+// exactがtrueの場合は、ちょうどwinLength個の列だけを勝利とする
+func checkWinAt(board [][]string, x, y int, symbol string, winLength int, exact bool) *models.WinLine {
+	if symbol == "" || !inBounds(board, x, y) || board[x][y] != symbol {
+		return nil
+	}
+	for _, d := range lineDirections {
+		cells := lineAt(board, x, y, d.dx, d.dy, symbol)
+		if len(cells) == winLength || (!exact && len(cells) > winLength) {
+			return &models.WinLine{Cells: cells, Direction: d.name}
+		}
+	}
+	return nil
+}
+
+// checkWin は盤面全体を走査し、symbolがwinLength個以上並んだ列があればそれを返す。
+// 任意の縦横サイズの盤面で、全ての行・列・斜めの列を調べる
+func checkWin(board [][]string, symbol string, winLength int) *models.WinLine {
+	for x := range board {
+		for y := range board[x] {
+			if symbol == "" || board[x][y] != symbol {
+				continue
+			}
+			for _, d := range lineDirections {
+				// 同じ列を重複して数えないよう、列の始点からのみ数える
+				if inBounds(board, x-d.dx, y-d.dy) && board[x-d.dx][y-d.dy] == symbol {
+					continue
+				}
+				cells := append([][2]int{{x, y}}, walk(board, x, y, d.dx, d.dy, symbol)...)
+				if len(cells) >= winLength {
+					return &models.WinLine{Cells: cells, Direction: d.name}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// LineWindows はルールの盤面でWinLength個のセルが並ぶ範囲（列を揃えられる範囲）を全て返す。
+// 3次元の盤面では層をまたぐ範囲、端がつながった盤面では端をまたぐ範囲を含み、
+// アルティメット三目並べでは小盤面ごとの範囲を返す。障害物や置かれた印は考慮しない。ボットの評価関数などが使う
+func LineWindows(rules models.GameRules) [][][2]int {
+	var windows [][][2]int
+	switch rules.Variant {
+	case models.VariantCube:
+		for z := 0; z < rules.BoardDepth; z++ {
+			for x := 0; x < rules.BoardHeight; x++ {
+				for y := 0; y < rules.BoardWidth; y++ {
+					for _, d := range cubeDirections {
+						end := rules.WinLength - 1
+						if !inCube(rules, x+end*d[0], y+end*d[1], z+end*d[2]) {
+							continue
+						}
+						window := make([][2]int, rules.WinLength)
+						for i := range window {
+							window[i] = boardCell(rules, x+i*d[0], y+i*d[1], z+i*d[2])
+						}
+						windows = append(windows, window)
+					}
+				}
+			}
+		}
+	case models.VariantUltimate:
+		for sx := 0; sx < rules.BoardHeight; sx += ultimateSubSize {
+			for sy := 0; sy < rules.BoardWidth; sy += ultimateSubSize {
+				windows = append(windows, planarWindows(ultimateSubSize, ultimateSubSize, ultimateSubSize, sx, sy)...)
+			}
+		}
+	case models.VariantToroidal:
+		for x := 0; x < rules.BoardHeight; x++ {
+			for y := 0; y < rules.BoardWidth; y++ {
+				for _, d := range lineDirections {
+					if rules.WinLength > wrapCycle(rules.BoardHeight, rules.BoardWidth, d.dx, d.dy) {
+						continue
+					}
+					window := make([][2]int, rules.WinLength)
+					for i := range window {
+						window[i] = [2]int{wrapIndex(x+i*d.dx, rules.BoardHeight), wrapIndex(y+i*d.dy, rules.BoardWidth)}
+					}
+					windows = append(windows, window)
+				}
+			}
+		}
+	default:
+		windows = planarWindows(rules.BoardHeight, rules.BoardWidth, rules.WinLength, 0, 0)
+	}
+	return windows
+}
+
+// height行width列の盤面でlength個のセルが並ぶ範囲を、(offsetX, offsetY)だけずらした座標で返すヘルパー関数
+func planarWindows(height, width, length, offsetX, offsetY int) [][][2]int {
+	var windows [][][2]int
+	for x := 0; x < height; x++ {
+		for y := 0; y < width; y++ {
+			for _, d := range lineDirections {
+				endX, endY := x+(length-1)*d.dx, y+(length-1)*d.dy
+				if endX < 0 || endY < 0 || endX >= height || endY >= width {
+					continue
+				}
+				window := make([][2]int, length)
+				for i := range window {
+					window[i] = [2]int{offsetX + x + i*d.dx, offsetY + y + i*d.dy}
+				}
+				windows = append(windows, window)
+			}
+		}
+	}
+	return windows
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 文字列の行から盤面を作るヘルパー関数。"."は空のセル
+func parseBoard(rows ...string) [][]string {
+	board := make([][]string, len(rows))
+	for x, row := range rows {
+		board[x] = make([]string, len(row))
+		for y, c := range row {
+			if c != '.' {
+				board[x][y] = string(c)
+			}
+		}
+	}
+	return board
+}
+
+// 15x15の空の盤面の行を返し、指定したセルにXを置くヘルパー関数
+func gomokuRows(cells ...[2]int) []string {
+	rows := make([][]byte, 15)
+	for i := range rows {
+		rows[i] = []byte(strings.Repeat(".", 15))
+	}
+	for _, cell := range cells {
+		rows[cell[0]][cell[1]] = 'X'
+	}
+	result := make([]string, len(rows))
+	for i, row := range rows {
+		result[i] = string(row)
+	}
+	return result
+}
+
+// (x, y)から(dx, dy)の方向にn個のセルを返すヘルパー関数
+func lineCells(x, y, dx, dy, n int) [][2]int {
+	cells := make([][2]int, n)
+	for i := range cells {
+		cells[i] = [2]int{x + i*dx, y + i*dy}
+	}
+	return cells
+}
+
+func TestCheckWinAt(t *testing.T) {
+	tests := []struct {
+		name          string
+		board         [][]string
+		x, y          int
+		winLength     int
+		exact         bool
+		wantCells     [][2]int
+		wantDirection string
+	}{
+		{
+			name:          "row on a rectangular board",
+			board:         parseBoard("......", "..XXX.", "......", "......"),
+			x:             1,
+			y:             3,
+			winLength:     3,
+			wantCells:     lineCells(1, 2, 0, 1, 3),
+			wantDirection: DirectionRow,
+		},
+		{
+			name:          "column on a tall board",
+			board:         parseBoard("...", ".O.", ".O.", ".O.", ".O.", "..."),
+			x:             4,
+			y:             1,
+			winLength:     4,
+			wantCells:     lineCells(1, 1, 1, 0, 4),
+			wantDirection: DirectionColumn,
+		},
+		{
+			name:          "diagonal ending in the bottom right corner",
+			board:         parseBoard("X....", ".X...", "..X..", "...X."),
+			x:             0,
+			y:             0,
+			winLength:     4,
+			wantCells:     lineCells(0, 0, 1, 1, 4),
+			wantDirection: DirectionDiagonal,
+		},
+		{
+			name:          "anti-diagonal touching the top right and left edges",
+			board:         parseBoard("....X", "...X.", "..X..", ".X...", "....."),
+			x:             2,
+			y:             2,
+			winLength:     4,
+			wantCells:     lineCells(0, 4, 1, -1, 4),
+			wantDirection: DirectionAntiDiagonal,
+		},
+		{
+			name:      "anti-diagonal cut short by the edge",
+			board:     parseBoard("..X", ".X.", "X..", "..."),
+			x:         1,
+			y:         1,
+			winLength: 4,
+		},
+		{
+			name:      "line broken by an opponent mark",
+			board:     parseBoard("XXOXX"),
+			x:         0,
+			y:         1,
+			winLength: 3,
+		},
+		{
+			name:          "exact length line",
+			board:         parseBoard(".XXXXX."),
+			x:             0,
+			y:             3,
+			winLength:     5,
+			exact:         true,
+			wantCells:     lineCells(0, 1, 0, 1, 5),
+			wantDirection: DirectionRow,
+		},
+		{
+			name:      "overline is not a win with exact length",
+			board:     parseBoard("XXXXXX."),
+			x:         0,
+			y:         5,
+			winLength: 5,
+			exact:     true,
+		},
+		{
+			name:          "overline wins without exact length",
+			board:         parseBoard("XXXXXX."),
+			x:             0,
+			y:             5,
+			winLength:     5,
+			wantCells:     lineCells(0, 0, 0, 1, 6),
+			wantDirection: DirectionRow,
+		},
+		{
+			name:      "one short of the win length",
+			board:     parseBoard("XXXX..."),
+			x:         0,
+			y:         0,
+			winLength: 5,
+		},
+		{
+			name:          "gomoku diagonal from the top left corner",
+			board:         parseBoard(gomokuRows(lineCells(0, 0, 1, 1, 5)...)...),
+			x:             4,
+			y:             4,
+			winLength:     5,
+			exact:         true,
+			wantCells:     lineCells(0, 0, 1, 1, 5),
+			wantDirection: DirectionDiagonal,
+		},
+		{
+			name:          "gomoku anti-diagonal into the bottom left corner",
+			board:         parseBoard(gomokuRows(lineCells(10, 4, 1, -1, 5)...)...),
+			x:             12,
+			y:             2,
+			winLength:     5,
+			exact:         true,
+			wantCells:     lineCells(10, 4, 1, -1, 5),
+			wantDirection: DirectionAntiDiagonal,
+		},
+		{
+			name:      "gomoku overline on the bottom row",
+			board:     parseBoard(gomokuRows(lineCells(14, 9, 0, 1, 6)...)...),
+			x:         14,
+			y:         14,
+			winLength: 5,
+			exact:     true,
+		},
+		{
+			name:      "empty cell",
+			board:     parseBoard("XX.", "...", "..."),
+			x:         0,
+			y:         2,
+			winLength: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol := tt.board[tt.x][tt.y]
+			line := checkWinAt(tt.board, tt.x, tt.y, symbol, tt.winLength, tt.exact)
+			if tt.wantCells == nil {
+				if line != nil {
+					t.Fatalf("checkWinAt() = %+v, want nil", line)
+				}
+				return
+			}
+			if line == nil {
+				t.Fatalf("checkWinAt() = nil, want %v", tt.wantCells)
+			}
+			if !reflect.DeepEqual(line.Cells, tt.wantCells) || line.Direction != tt.wantDirection {
+				t.Errorf("checkWinAt() = %v %s, want %v %s", line.Cells, line.Direction, tt.wantCells, tt.wantDirection)
+			}
+		})
+	}
+}
+
+func TestCheckWin(t *testing.T) {
+	tests := []struct {
+		name          string
+		board         [][]string
+		symbol        string
+		winLength     int
+		wantCells     [][2]int
+		wantDirection string
+	}{
+		{
+			name:          "row on a wide board",
+			board:         parseBoard(".......", "...OOOO", "......."),
+			symbol:        "O",
+			winLength:     4,
+			wantCells:     lineCells(1, 3, 0, 1, 4),
+			wantDirection: DirectionRow,
+		},
+		{
+			name:          "column along the right edge of a tall board",
+			board:         parseBoard("..X", "..X", "..X", "...", "..."),
+			symbol:        "X",
+			winLength:     3,
+			wantCells:     lineCells(0, 2, 1, 0, 3),
+			wantDirection: DirectionColumn,
+		},
+		{
+			name:          "diagonal in the bottom left of a rectangular board",
+			board:         parseBoard("......", "X.....", ".X....", "..X...", "...X.."),
+			symbol:        "X",
+			winLength:     4,
+			wantCells:     lineCells(1, 0, 1, 1, 4),
+			wantDirection: DirectionDiagonal,
+		},
+		{
+			name:          "anti-diagonal on a wide board",
+			board:         parseBoard(".....X", "....X.", "...X.."),
+			symbol:        "X",
+			winLength:     3,
+			wantCells:     lineCells(0, 5, 1, -1, 3),
+			wantDirection: DirectionAntiDiagonal,
+		},
+		{
+			name:      "other symbol does not count",
+			board:     parseBoard("OOO", "...", "..."),
+			symbol:    "X",
+			winLength: 3,
+		},
+		{
+			name:      "no line on a full drawn board",
+			board:     parseBoard("XOX", "XOO", "OXX"),
+			symbol:    "X",
+			winLength: 3,
+		},
+		{
+			name:          "whole overline is returned",
+			board:         parseBoard(gomokuRows(lineCells(3, 7, 1, 0, 7)...)...),
+			symbol:        "X",
+			winLength:     5,
+			wantCells:     lineCells(3, 7, 1, 0, 7),
+			wantDirection: DirectionColumn,
+		},
+		{
+			name:      "gomoku four is not a win",
+			board:     parseBoard(gomokuRows(lineCells(11, 11, 1, 1, 4)...)...),
+			symbol:    "X",
+			winLength: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := checkWin(tt.board, tt.symbol, tt.winLength)
+			if tt.wantCells == nil {
+				if line != nil {
+					t.Fatalf("checkWin() = %+v, want nil", line)
+				}
+				return
+			}
+			if line == nil {
+				t.Fatalf("checkWin() = nil, want %v", tt.wantCells)
+			}
+			if !reflect.DeepEqual(line.Cells, tt.wantCells) || line.Direction != tt.wantDirection {
+				t.Errorf("checkWin() = %v %s, want %v %s", line.Cells, line.Direction, tt.wantCells, tt.wantDirection)
+			}
+		})
+	}
+}
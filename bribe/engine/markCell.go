@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"xicserver/models"
+)
+
+func applyMarkCell(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	// 同時手番では手番のチェックの代わりに、提出を溜めて全員が揃った時点で公開する
+	if game.Rules.Simultaneous {
+		return applyCommitMove(game, action, randGen)
+	}
+	// 量子三目並べでは1手で2つのセルに重ね合わせの印を置く
+	if game.Rules.Variant == models.VariantQuantum {
+		return applyQuantumMove(game, action, randGen)
+	}
+
+	// 選択されたセルを盤面の座標に変換（3次元の盤面では層ごとに行を並べた座標になる）
+	variantRules := variantFor(game.Rules)
+	selected, err := variantRules.locate(game, action)
+	if err != nil {
+		return nil, err
+	}
+	x, y := selected[0], selected[1]
+
+	// 選択されたセルが空かどうかチェック
+	if game.Board[x][y] == ObstacleMark {
+		return nil, ErrCellBlocked
+	}
+	if game.Board[x][y] != "" {
+		// 霧のルールでは、手番のプレイヤーが見えない印のセルを選んだ場合にそのセルを公開する
+		if game.CurrentTurn == action.PlayerID && game.Status == StatusInProgress && !isVisibleTo(game, action.PlayerID, selected) {
+			return revealByFailedMark(game, action.PlayerID, selected), nil
+		}
+		return nil, ErrCellMarked
+	}
+
+	// プレイヤーのIDがCurrentTurnと一致するか確認
+	if game.CurrentTurn != action.PlayerID {
+		return nil, ErrNotYourTurn
+	}
+
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	// 置く印は通常プレイヤーのシンボル。OrderとChaosではプレイヤーが毎手選ぶ
+	symbol, err := markSymbol(game, action)
+	if err != nil {
+		return nil, err
+	}
+
+	// バリエーション固有の配置ルール（連珠の禁じ手、アルティメットの小盤面の指定など）
+	if err := variantRules.checkMove(game, action.PlayerID, x, y); err != nil {
+		return nil, err
+	}
+
+	// 持ち時間を使い切っていれば、手を記録せず印を置く前にマッチを終了
+	clocks := copyClocks(game.Clocks)
+	if !chargeClock(game, action.PlayerID, action.At) {
+		return flagPlayer(game, action.PlayerID), nil
+	}
+	recordMove(game, action.PlayerID, clocks)
+
+	advantage := biasAdvantage(game, action.PlayerID)
+
+	cell := [2]int{x, y}
+	if advantage <= 0 && !(advantage == 0 && randGen.Float64() < game.Rules.MarkAccuracy) {
+		// 審判が選択どおりに置かない場合は、バリエーションごとの候補（通常は他の空のセル）からランダムに選ぶ
+		// 候補が存在しない場合は、選択されたセルに印を置く
+		emptyCells := variantRules.alternatives(game, action.PlayerID, selected)
+		if len(emptyCells) > 0 {
+			cell = emptyCells[randGen.Intn(len(emptyCells))]
+		}
+	}
+	game.Board[cell[0]][cell[1]] = symbol
+
+	return finishMove(game, cell, action.At, randGen), nil
+}
+
+// 印が置かれた後の審判のカウントダウンと、置かれたセルを含む列の勝敗判定を行うヘルパー関数
+func finishMove(game *models.Game, cell [2]int, now time.Time, randGen *rand.Rand) []Event {
+	x, y, z := boardCoords(game.Rules, cell)
+	game.LastMove = &models.Move{
+		PlayerID: game.CurrentTurn,
+		X:        x,
+		Y:        y,
+		Z:        z,
+		Symbol:   game.Board[cell[0]][cell[1]],
+	}
+
+	// 審判の状態とカウントダウンを管理
+	events := refereeCountdown(game, randGen)
+
+	// 勝敗判定とゲーム状態の更新
+	return append(events, checkAndUpdateGameStatus(game, cell, now)...)
+}
+
+// 1手ごとに審判の状態が固定される残りの手数を減らすヘルパー関数
+func refereeCountdown(game *models.Game, randGen *rand.Rand) []Event {
+	if game.RefereeCount == 0 {
+		return nil
+	}
+	game.RefereeCount--
+
+	// RefereeCountが0になったらRefereeStatusを"normal"で始まる状態に戻す
+	if game.RefereeCount == 0 && !strings.HasPrefix(game.RefereeStatus, "normal") {
+		game.RefereeStatus = RandomNormalRefereeStatus(randGen)
+		return []Event{messageAll("REFEREE: Now I'm reformed and fair!")}
+	}
+	return nil
+}
+
+func checkAndUpdateGameStatus(game *models.Game, cell [2]int, now time.Time) []Event {
+	if events, done := judgeCell(game, cell, game.CurrentTurn); done {
+		return events
+	}
+
+	// ゲームが続行する場合、ターン更新
+	StartTurn(game, nextPlayerID(game, game.CurrentTurn), now)
+	return []Event{{Type: EventGameState}}
+}
+
+// セルの印でラウンドが決着したかを判定し、決着した場合はラウンドを終了するヘルパー関数。
+// ownerIDはセルの印を持つプレイヤーで、勝敗判定はバリエーションごとの規則に従う
+func judgeCell(game *models.Game, cell [2]int, ownerID uint) ([]Event, bool) {
+	winnerID, winLine, done := variantFor(game.Rules).judge(game, cell, ownerID, game.Board[cell[0]][cell[1]])
+	if !done {
+		return nil, false
+	}
+	// ミゼールでは列を完成させたプレイヤーの負け。列は負けを決めた列として記録する
+	if game.Rules.Misere && winnerID != 0 {
+		winnerID = opponentID(game, winnerID)
+	}
+	if winLine != nil {
+		// 勝者がいる場合は、勝利を決めた手の番号も記録する
+		winLine.MoveNumber = len(game.History)
+	}
+	return finishRound(game, winnerID, winLine), true
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestWinningLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    *models.WinLine
+	}{
+		{
+			name:    "row",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 1, 0), mark(testX, 0, 1), mark(testO, 1, 1), mark(testX, 0, 2)},
+			want:    &models.WinLine{Cells: [][2]int{{0, 0}, {0, 1}, {0, 2}}, Direction: DirectionRow, MoveNumber: 5},
+		},
+		{
+			name:    "column",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 0, 1), mark(testX, 1, 0), mark(testO, 1, 1), mark(testX, 2, 0)},
+			want:    &models.WinLine{Cells: [][2]int{{0, 0}, {1, 0}, {2, 0}}, Direction: DirectionColumn, MoveNumber: 5},
+		},
+		{
+			name:    "diagonal",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 0, 1), mark(testX, 1, 1), mark(testO, 0, 2), mark(testX, 2, 2)},
+			want:    &models.WinLine{Cells: [][2]int{{0, 0}, {1, 1}, {2, 2}}, Direction: DirectionDiagonal, MoveNumber: 5},
+		},
+		{
+			name:    "anti-diagonal",
+			actions: []Action{mark(testX, 0, 2), mark(testO, 0, 0), mark(testX, 1, 1), mark(testO, 0, 1), mark(testX, 2, 0)},
+			want:    &models.WinLine{Cells: [][2]int{{0, 2}, {1, 1}, {2, 0}}, Direction: DirectionAntiDiagonal, MoveNumber: 5},
+		},
+		{
+			name:    "second player wins on move six",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 1, 0), mark(testX, 0, 1), mark(testO, 1, 1), mark(testX, 2, 2), mark(testO, 1, 2)},
+			want:    &models.WinLine{Cells: [][2]int{{1, 0}, {1, 1}, {1, 2}}, Direction: DirectionRow, MoveNumber: 6},
+		},
+		{
+			// ラウンドの投了のように列が揃わずに決着した場合は列を持たない
+			name:    "resign",
+			actions: []Action{mark(testX, 0, 0), {Type: ActionResign, PlayerID: testO, Scope: ScopeRound}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _ := applyAll(t, newTestGame(t, testRules()), rand.New(rand.NewSource(1)), tt.actions...)
+			if len(game.WinningLines) != 1 {
+				t.Fatalf("WinningLines = %+v, want one line", game.WinningLines)
+			}
+			if got := game.WinningLines[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WinningLines[0] = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"math/rand"
+
+	"xicserver/models"
+)
+
+// ObstacleMark は印を置けないブロックされたセルを表す盤面の値
+const ObstacleMark = "#"
+
+// 勝利可能な配置が見つかるまでに障害物の配置をやり直す回数の上限
+const obstacleAttempts = 100
+
+// ルール設定の数だけ障害物をランダムに配置するヘルパー関数。
+// 少なくとも1本はWinLength個の列を揃えられる配置になるまでやり直す。上限まで見つからない場合は障害物を置かずにエラーを返す
+func placeObstacles(game *models.Game, randGen *rand.Rand) error {
+	count := game.Rules.Obstacles
+	if count <= 0 {
+		return nil
+	}
+	height, width := len(game.Board), len(game.Board[0])
+	for attempt := 0; attempt < obstacleAttempts; attempt++ {
+		board := copyBoard(game.Board)
+		for _, i := range randGen.Perm(height * width)[:count] {
+			board[i/width][i%width] = ObstacleMark
+		}
+		if isWinnable(game.Rules, board) {
+			game.Board = board
+			return nil
+		}
+	}
+	return ErrNoObstacleLayout
+}
+
+// 障害物を含まないWinLength個の連続したセルが、少なくとも1本あるかを返すヘルパー関数。
+// 3次元の盤面では層ごとに調べる。どのバリエーションでも、層の中の直線は列として数えられる
+func isWinnable(rules models.GameRules, board [][]string) bool {
+	planes := BoardLayers(rules, board)
+	if planes == nil {
+		planes = [][][]string{board}
+	}
+	for _, plane := range planes {
+		for x := range plane {
+			for y := range plane[x] {
+				for _, d := range lineDirections {
+					if isOpenWindow(plane, x, y, d.dx, d.dy, rules.WinLength) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// (x, y)から(dx, dy)の方向のlength個のセルが、盤面内で障害物を含まないかを返すヘルパー関数
+func isOpenWindow(board [][]string, x, y, dx, dy, length int) bool {
+	for i := 0; i < length; i++ {
+		cx, cy := x+i*dx, y+i*dy
+		if !inBounds(board, cx, cy) || board[cx][cy] == ObstacleMark {
+			return false
+		}
+	}
+	return true
+}
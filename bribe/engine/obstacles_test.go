@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestPlaceObstacles(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height, length int
+		obstacles             int
+	}{
+		{name: "3x3", width: 3, height: 3, length: 3, obstacles: 3},
+		{name: "5x5", width: 5, height: 5, length: 4, obstacles: 8},
+		{name: "wide board", width: 7, height: 4, length: 4, obstacles: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.BoardWidth, rules.BoardHeight, rules.WinLength = tt.width, tt.height, tt.length
+			rules.Obstacles = tt.obstacles
+
+			for seed := int64(1); seed <= 10; seed++ {
+				randGen := rand.New(rand.NewSource(seed))
+				game := newTestGame(t, rules)
+				if err := ResetBoard(game, randGen); err != nil {
+					t.Fatalf("seed %d: ResetBoard returned error: %v", seed, err)
+				}
+				if got := countMarks(game.Board); got != tt.obstacles {
+					t.Fatalf("seed %d: board has %d obstacles, want %d", seed, got, tt.obstacles)
+				}
+
+				// 障害物のない列にXを置き、Oは列の外に置いてXが勝てることを確かめる
+				windows := LineWindows(rules)
+				i := slices.IndexFunc(windows, func(window [][2]int) bool {
+					return !slices.ContainsFunc(window, func(cell [2]int) bool { return game.Board[cell[0]][cell[1]] == ObstacleMark })
+				})
+				if i == -1 {
+					t.Fatalf("seed %d: no open line on %v", seed, game.Board)
+				}
+				others := getEmptyCellsExcept(game.Board, -1, -1)
+				others = slices.DeleteFunc(others, func(cell [2]int) bool { return slices.Contains(windows[i], cell) })
+
+				var actions []Action
+				for j, cell := range windows[i] {
+					actions = append(actions, mark(testX, cell[0], cell[1]))
+					if j < len(windows[i])-1 {
+						actions = append(actions, mark(testO, others[j][0], others[j][1]))
+					}
+				}
+				game, _ = applyAll(t, game, randGen, actions...)
+				if game.Status != StatusRoundFinished || game.Winners[len(game.Winners)-1] != testX {
+					t.Errorf("seed %d: status %q, winners %v, want a win for X along %v", seed, game.Status, game.Winners, windows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceObstaclesWithoutLayout(t *testing.T) {
+	// 3x3の盤面に7つの障害物を置くと、3つのセルを揃えられる列は残らない
+	rules := testRules()
+	rules.Obstacles = 7
+	game := newTestGame(t, rules)
+
+	if err := ResetBoard(game, rand.New(rand.NewSource(1))); !errors.Is(err, ErrNoObstacleLayout) {
+		t.Fatalf("ResetBoard error = %v, want %v", err, ErrNoObstacleLayout)
+	}
+	if got := countMarks(game.Board); got != 0 {
+		t.Errorf("board has %d obstacles, want none", got)
+	}
+}
+
+func TestRetryReportsMissingObstacles(t *testing.T) {
+	rules := testRules()
+	rules.Obstacles = 7
+	game := newTestGame(t, rules)
+	game.Status = StatusRoundFinished
+
+	retry := func(playerID uint) Action {
+		return Action{Type: ActionRetry, PlayerID: playerID, WantRetry: true, At: testStart}
+	}
+	game, events := applyAll(t, game, rand.New(rand.NewSource(1)), retry(testX), retry(testO))
+	if game.Status != StatusInProgress {
+		t.Fatalf("status = %q, want %q", game.Status, StatusInProgress)
+	}
+	if !slices.ContainsFunc(events, func(event Event) bool { return event.Type == EventSystemMessage && event.To == 0 }) {
+		t.Errorf("events = %+v, want a system message to all players", events)
+	}
+}
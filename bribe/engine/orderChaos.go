@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"math/rand"
+
+	"xicserver/models"
+)
+
+// OrderとChaosで置ける印
+var orderChaosSymbols = [...]string{"X", "O"}
+
+// orderChaosVariant はOrderとChaosのルール。両プレイヤーが毎手XとOのどちらかを選んで置き、
+// Orderはどちらかの印で列を揃えれば勝ち、Chaosは列が揃わないまま盤面が埋まれば勝ちとなる
+type orderChaosVariant struct {
+	standardVariant
+}
+
+func (orderChaosVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	orderID := OrderPlayer(game)
+	if winLine := checkWinAt(game.Board, cell[0], cell[1], symbol, game.Rules.WinLength, game.Rules.ExactLength); winLine != nil {
+		return orderID, winLine, true
+	}
+	if isBoardFull(game.Board) {
+		return opponentID(game, orderID), nil, true
+	}
+	return 0, nil, false
+}
+
+// OrderPlayer はOrderとChaosでOrderを担当するプレイヤーのIDを返す。ラウンドの開始時に記録した先手がOrderとなり、
+// 再戦のたびに先手を交代して役割を入れ替える。OrderとChaos以外のルールでは0
+func OrderPlayer(game *models.Game) uint {
+	if game.Rules.Variant != models.VariantOrderChaos {
+		return 0
+	}
+	return firstMover(game)
+}
+
+// プレイヤーが置く印を返すヘルパー関数。OrderとChaosではアクションで選んだ印、それ以外はプレイヤーのシンボル
+func markSymbol(game *models.Game, action Action) (string, error) {
+	if game.Rules.Variant != models.VariantOrderChaos {
+		return playerSymbol(game, action.PlayerID), nil
+	}
+	for _, symbol := range orderChaosSymbols {
+		if action.Symbol == symbol {
+			return symbol, nil
+		}
+	}
+	return "", ErrInvalidSymbol
+}
+
+// タイムアウト時に置く印を返すヘルパー関数。OrderとChaosではXとOからランダムに選ぶ
+func randomMarkSymbol(game *models.Game, playerID uint, randGen *rand.Rand) string {
+	if game.Rules.Variant != models.VariantOrderChaos {
+		return playerSymbol(game, playerID)
+	}
+	return orderChaosSymbols[randGen.Intn(len(orderChaosSymbols))]
+}
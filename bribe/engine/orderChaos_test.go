@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+// OrderとChaosのテストで使う、6x6の盤面でちょうど5個の列を揃えるルールを返すヘルパー関数
+func orderChaosRules() models.GameRules {
+	rules := testRules()
+	rules.Variant = models.VariantOrderChaos
+	rules.BoardWidth, rules.BoardHeight, rules.WinLength = 6, 6, 5
+	rules.ExactLength = true
+	return rules
+}
+
+func TestJudgeCell(t *testing.T) {
+	misere := testRules()
+	misere.Misere = true
+	tests := []struct {
+		name        string
+		rules       models.GameRules
+		board       []string
+		cell        [2]int
+		owner       uint
+		wantDone    bool
+		wantWinners []uint
+	}{
+		{
+			name:  "order wins with an exact five of either symbol",
+			rules: orderChaosRules(),
+			board: []string{
+				"OOOOO.",
+				"......",
+				"......",
+				"......",
+				"......",
+				"......",
+			},
+			cell: [2]int{0, 4}, owner: testO, wantDone: true, wantWinners: []uint{testX},
+		},
+		{
+			name:  "an overline does not win for order",
+			rules: orderChaosRules(),
+			board: []string{
+				"XXXXXX",
+				"......",
+				"......",
+				"......",
+				"......",
+				"......",
+			},
+			cell: [2]int{0, 5}, owner: testX,
+		},
+		{
+			name:  "a full board without a line is a chaos win",
+			rules: orderChaosRules(),
+			board: []string{
+				"XOXOXO",
+				"XOXOXO",
+				"OXOXOX",
+				"OXOXOX",
+				"XOXOXO",
+				"XOXOXO",
+			},
+			cell: [2]int{5, 5}, owner: testO, wantDone: true, wantWinners: []uint{testO},
+		},
+		{
+			name:  "misere: completing a line loses",
+			rules: misere,
+			board: []string{
+				"XXX",
+				"OO.",
+				"...",
+			},
+			cell: [2]int{0, 2}, owner: testX, wantDone: true, wantWinners: []uint{testO},
+		},
+		{
+			name:  "misere: a full board without a line is a draw",
+			rules: misere,
+			board: []string{
+				"XOX",
+				"XOO",
+				"OXX",
+			},
+			cell: [2]int{2, 2}, owner: testX, wantDone: true, wantWinners: []uint{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestGame(t, tt.rules)
+			game.Board = parseBoard(tt.board...)
+
+			_, done := judgeCell(game, tt.cell, tt.owner)
+			if done != tt.wantDone {
+				t.Fatalf("judgeCell() done = %v, want %v", done, tt.wantDone)
+			}
+			if !slices.Equal(game.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", game.Winners, tt.wantWinners)
+			}
+		})
+	}
+}
+
+func TestOrderPlayer(t *testing.T) {
+	rules := orderChaosRules()
+	rules.TurnTimeLimit = 10
+	rules.TimeoutPenalty = models.TimeoutSkipTurn
+	randGen := rand.New(rand.NewSource(1))
+
+	// 最初の手の前に時間切れで手番が飛ばされても、先手のXがOrderのまま
+	skipped, _ := applyAll(t, newTestGame(t, rules), randGen, Action{Type: ActionTimeout, At: testStart.Add(10 * time.Second)})
+	if skipped.CurrentTurn != testO || OrderPlayer(skipped) != testX {
+		t.Fatalf("CurrentTurn = %d, OrderPlayer = %d, want %d and %d", skipped.CurrentTurn, OrderPlayer(skipped), testO, testX)
+	}
+
+	// 次のラウンドではOrderとChaosが入れ替わり、Orderが先手となる
+	next, _ := applyAll(t, skipped, randGen,
+		Action{Type: ActionResign, PlayerID: testO, Scope: ScopeRound, At: testStart},
+		Action{Type: ActionRetry, PlayerID: testX, WantRetry: true},
+		Action{Type: ActionRetry, PlayerID: testO, WantRetry: true},
+	)
+	if next.Round != 2 || OrderPlayer(next) != testO || next.CurrentTurn != testO {
+		t.Errorf("Round = %d, OrderPlayer = %d, CurrentTurn = %d, want 2, %d and %d", next.Round, OrderPlayer(next), next.CurrentTurn, testO, testO)
+	}
+}
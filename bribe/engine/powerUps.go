@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"math/rand"
+	"time"
+
+	"xicserver/models"
+)
+
+// マッチで配られるパワーアップの種類
+var powerUpKinds = [...]string{models.PowerUpErase, models.PowerUpSwap, models.PowerUpShield}
+
+// マッチの開始時に各プレイヤーにパワーアップを配るヘルパー関数。パワーアップのないルールではnil
+func dealPowerUps(game *models.Game) map[uint]map[string]int {
+	if game.Rules.PowerUps <= 0 {
+		return nil
+	}
+	hands := make(map[uint]map[string]int)
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		hand := make(map[string]int, len(powerUpKinds))
+		for _, kind := range powerUpKinds {
+			hand[kind] = game.Rules.PowerUps
+		}
+		hands[player.ID] = hand
+	}
+	return hands
+}
+
+// パワーアップの手札の複製を返すヘルパー関数
+func copyHands(hands map[uint]map[string]int) map[uint]map[string]int {
+	if hands == nil {
+		return nil
+	}
+	copied := make(map[uint]map[string]int, len(hands))
+	for playerID, hand := range hands {
+		copied[playerID] = make(map[string]int, len(hand))
+		for kind, count := range hand {
+			copied[playerID][kind] = count
+		}
+	}
+	return copied
+}
+
+// セルがシールドで置かれた印かどうかを返すヘルパー関数
+func isShielded(game *models.Game, cell [2]int) bool {
+	for _, shielded := range game.Shielded {
+		if shielded == cell {
+			return true
+		}
+	}
+	return false
+}
+
+// パワーアップを使う。使用は1手として数え、審判のカウントダウンが進み、手番が相手に移る
+func applyPowerUp(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	if game.Rules.PowerUps <= 0 {
+		return nil, ErrPowerUpsDisabled
+	}
+	if game.CurrentTurn != action.PlayerID {
+		return nil, ErrNotYourTurn
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	if game.Hands[action.PlayerID][action.PowerUp] <= 0 {
+		return nil, ErrNoPowerUp
+	}
+
+	target := [2]int{action.X, action.Y}
+	var err error
+	switch action.PowerUp {
+	case models.PowerUpErase:
+		err = checkEraseTarget(game, action.PlayerID, target)
+	case models.PowerUpSwap:
+		err = checkSwapTargets(game, action.PlayerID, target, [2]int{action.X2, action.Y2})
+	case models.PowerUpShield:
+		err = checkShieldTarget(game, action.PlayerID, target)
+	default:
+		err = ErrNoPowerUp
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 持ち時間を使い切っていれば、手を記録せずパワーアップを使う前にマッチを終了
+	clocks := copyClocks(game.Clocks)
+	if !chargeClock(game, action.PlayerID, action.At) {
+		return flagPlayer(game, action.PlayerID), nil
+	}
+	recordMove(game, action.PlayerID, clocks)
+	game.Hands[action.PlayerID][action.PowerUp]--
+
+	switch action.PowerUp {
+	case models.PowerUpErase:
+		game.Board[target[0]][target[1]] = ""
+		events := []Event{messageAll("SYSTEM: A mark was erased by a power-up!")}
+		events = append(events, refereeCountdown(game, randGen)...)
+		StartTurn(game, nextPlayerID(game, action.PlayerID), action.At)
+		return append(events, Event{Type: EventGameState}), nil
+	case models.PowerUpSwap:
+		other := [2]int{action.X2, action.Y2}
+		game.Board[target[0]][target[1]], game.Board[other[0]][other[1]] = game.Board[other[0]][other[1]], game.Board[target[0]][target[1]]
+		events := []Event{messageAll("SYSTEM: Two marks were swapped by a power-up!")}
+		events = append(events, refereeCountdown(game, randGen)...)
+		return append(events, judgeSwap(game, action.PlayerID, target, other, action.At)...), nil
+	default:
+		// シールドの印は審判にずらされず、選択したセルにそのまま置かれる
+		game.Board[target[0]][target[1]] = playerSymbol(game, action.PlayerID)
+		game.Shielded = append(game.Shielded, target)
+		events := []Event{messageAll("SYSTEM: A shielded mark was placed!")}
+		return append(events, finishMove(game, target, action.At, randGen)...), nil
+	}
+}
+
+// 消去の対象が、シールドされていない相手の印かどうかを検証するヘルパー関数
+func checkEraseTarget(game *models.Game, playerID uint, cell [2]int) error {
+	if !inBounds(game.Board, cell[0], cell[1]) || game.Board[cell[0]][cell[1]] != playerSymbol(game, opponentID(game, playerID)) {
+		return ErrInvalidTarget
+	}
+	if isShielded(game, cell) {
+		return ErrCellShielded
+	}
+	return nil
+}
+
+// 入れ替えの対象が、シールドされていない自分の印と相手の印の組かどうかを検証するヘルパー関数
+func checkSwapTargets(game *models.Game, playerID uint, first, second [2]int) error {
+	if !inBounds(game.Board, first[0], first[1]) || !inBounds(game.Board, second[0], second[1]) {
+		return ErrInvalidTarget
+	}
+	own, opponent := playerSymbol(game, playerID), playerSymbol(game, opponentID(game, playerID))
+	marks := [2]string{game.Board[first[0]][first[1]], game.Board[second[0]][second[1]]}
+	if marks != [2]string{own, opponent} && marks != [2]string{opponent, own} {
+		return ErrInvalidTarget
+	}
+	if isShielded(game, first) || isShielded(game, second) {
+		return ErrCellShielded
+	}
+	return nil
+}
+
+// シールドの対象が、自分が印を置ける空のセルかどうかを検証するヘルパー関数
+func checkShieldTarget(game *models.Game, playerID uint, cell [2]int) error {
+	if !inBounds(game.Board, cell[0], cell[1]) {
+		return ErrInvalidCell
+	}
+	switch game.Board[cell[0]][cell[1]] {
+	case "":
+	case ObstacleMark:
+		return ErrCellBlocked
+	default:
+		return ErrCellMarked
+	}
+	return variantFor(game.Rules).checkMove(game, playerID, cell[0], cell[1])
+}
+
+// 入れ替えた2つのセルの勝敗判定を行うヘルパー関数。
+// 両方のプレイヤーの列が同時に揃った場合は、入れ替えたプレイヤーの列を優先する
+func judgeSwap(game *models.Game, playerID uint, first, second [2]int, now time.Time) []Event {
+	opponent := opponentID(game, playerID)
+	cells := []struct {
+		cell    [2]int
+		ownerID uint
+	}{{first, playerID}, {second, opponent}}
+	if game.Board[first[0]][first[1]] != playerSymbol(game, playerID) {
+		cells[0].cell, cells[1].cell = second, first
+	}
+	for _, c := range cells {
+		if events, done := judgeCell(game, c.cell, c.ownerID); done {
+			return events
+		}
+	}
+	StartTurn(game, opponent, now)
+	return []Event{{Type: EventGameState}}
+}
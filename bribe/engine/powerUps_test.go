@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"xicserver/models"
+)
+
+// パワーアップを使うアクションを返すヘルパー関数。入れ替えでは2つ目のセルも指定する
+func usePowerUp(playerID uint, kind string, cells ...[2]int) Action {
+	action := Action{Type: ActionPowerUp, PlayerID: playerID, PowerUp: kind, X: cells[0][0], Y: cells[0][1], At: testStart}
+	if len(cells) > 1 {
+		action.X2, action.Y2 = cells[1][0], cells[1][1]
+	}
+	return action
+}
+
+func TestApplyPowerUp(t *testing.T) {
+	bribe := Action{Type: ActionBribe, PlayerID: testO, At: testStart}
+	tests := []struct {
+		name         string
+		powerUps     int
+		actions      []Action
+		powerUp      Action
+		wantErr      error
+		wantBoard    []string
+		wantShielded [][2]int
+		wantLeft     int // Xの残りの枚数
+	}{
+		{
+			name:      "erase removes an opponent mark",
+			powerUps:  1,
+			actions:   []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			powerUp:   usePowerUp(testX, models.PowerUpErase, [2]int{1, 1}),
+			wantBoard: []string{"X..", "...", "..."},
+		},
+		{
+			name:     "erase rejects an own mark",
+			powerUps: 1,
+			actions:  []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			powerUp:  usePowerUp(testX, models.PowerUpErase, [2]int{0, 0}),
+			wantErr:  ErrInvalidTarget,
+		},
+		{
+			name:     "erase rejects a shielded mark",
+			powerUps: 1,
+			actions:  []Action{mark(testX, 0, 0), usePowerUp(testO, models.PowerUpShield, [2]int{1, 1})},
+			powerUp:  usePowerUp(testX, models.PowerUpErase, [2]int{1, 1}),
+			wantErr:  ErrCellShielded,
+		},
+		{
+			name:      "swap exchanges an own and an opponent mark",
+			powerUps:  1,
+			actions:   []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			powerUp:   usePowerUp(testX, models.PowerUpSwap, [2]int{0, 0}, [2]int{1, 1}),
+			wantBoard: []string{"O..", ".X.", "..."},
+		},
+		{
+			name:     "swap rejects two own marks",
+			powerUps: 1,
+			actions:  []Action{mark(testX, 0, 0), mark(testO, 1, 1), mark(testX, 2, 2), mark(testO, 2, 0)},
+			powerUp:  usePowerUp(testX, models.PowerUpSwap, [2]int{0, 0}, [2]int{2, 2}),
+			wantErr:  ErrInvalidTarget,
+		},
+		{
+			name:     "swap rejects a shielded mark",
+			powerUps: 1,
+			actions:  []Action{mark(testX, 0, 0), usePowerUp(testO, models.PowerUpShield, [2]int{1, 1})},
+			powerUp:  usePowerUp(testX, models.PowerUpSwap, [2]int{0, 0}, [2]int{1, 1}),
+			wantErr:  ErrCellShielded,
+		},
+		{
+			// 審判が相手に傾いていても、シールドの印は選択したセルに置かれる
+			name:         "shield places a mark the referee cannot move",
+			powerUps:     1,
+			actions:      []Action{bribe},
+			powerUp:      usePowerUp(testX, models.PowerUpShield, [2]int{2, 2}),
+			wantBoard:    []string{"...", "...", "..X"},
+			wantShielded: [][2]int{{2, 2}},
+		},
+		{
+			name:     "shield rejects a marked cell",
+			powerUps: 1,
+			actions:  []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			powerUp:  usePowerUp(testX, models.PowerUpShield, [2]int{1, 1}),
+			wantErr:  ErrCellMarked,
+		},
+		{
+			name:     "runs out after the dealt count",
+			powerUps: 1,
+			actions: []Action{
+				mark(testX, 0, 0), mark(testO, 1, 1),
+				usePowerUp(testX, models.PowerUpErase, [2]int{1, 1}), mark(testO, 2, 2),
+			},
+			powerUp: usePowerUp(testX, models.PowerUpErase, [2]int{2, 2}),
+			wantErr: ErrNoPowerUp,
+		},
+		{
+			name:      "second use with two dealt",
+			powerUps:  2,
+			actions:   []Action{mark(testX, 0, 0), mark(testO, 1, 1), usePowerUp(testX, models.PowerUpErase, [2]int{1, 1}), mark(testO, 2, 2)},
+			powerUp:   usePowerUp(testX, models.PowerUpErase, [2]int{2, 2}),
+			wantBoard: []string{"X..", "...", "..."},
+		},
+		{
+			name:    "disabled without power-ups",
+			actions: []Action{mark(testX, 0, 0), mark(testO, 1, 1)},
+			powerUp: usePowerUp(testX, models.PowerUpErase, [2]int{1, 1}),
+			wantErr: ErrPowerUpsDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.PowerUps = tt.powerUps
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, rules), randGen, tt.actions...)
+
+			next, _, err := Apply(game, tt.powerUp, randGen)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply returned error: %v", err)
+			}
+			if want := parseBoard(tt.wantBoard...); !reflect.DeepEqual(next.Board, want) {
+				t.Errorf("Board = %v, want %v", next.Board, want)
+			}
+			if !reflect.DeepEqual(next.Shielded, tt.wantShielded) {
+				t.Errorf("Shielded = %v, want %v", next.Shielded, tt.wantShielded)
+			}
+			if left := next.Hands[testX][tt.powerUp.PowerUp]; left != tt.wantLeft {
+				t.Errorf("%s left = %d, want %d", tt.powerUp.PowerUp, left, tt.wantLeft)
+			}
+			if next.CurrentTurn != testO {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, testO)
+			}
+		})
+	}
+}
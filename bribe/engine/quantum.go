@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"xicserver/models"
+)
+
+// quantumVariant は量子三目並べ。各手で2つのセルに重ね合わせの印を置き、セルを頂点、手を辺とするもつれのグラフを作る。
+// 手がグラフに循環を作ると、相手がその手の2つのセルのどちらに確定させるかを選んで測定し、
+// 循環につながる全ての印が連鎖的に確定する。列の判定には確定した印だけを使う
+type quantumVariant struct{}
+
+func (quantumVariant) reset(game *models.Game) {
+	game.Board = NewBoard(game.Rules)
+	subscripts := make([][]int, len(game.Board))
+	for i, row := range game.Board {
+		subscripts[i] = make([]int, len(row))
+	}
+	game.Quantum = &models.QuantumState{Subscripts: subscripts}
+}
+
+func (quantumVariant) locate(game *models.Game, action Action) ([2]int, error) {
+	return locatePlanar(game, action)
+}
+
+func (quantumVariant) checkMove(game *models.Game, playerID uint, x, y int) error {
+	if game.Quantum.Collapse != nil {
+		return ErrCollapsePending
+	}
+	return nil
+}
+
+func (quantumVariant) candidates(game *models.Game, playerID uint) [][2]int {
+	return getEmptyCellsExcept(game.Board, -1, -1)
+}
+
+func (v quantumVariant) alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int {
+	return anyOtherCell(v, game, playerID, selected)
+}
+
+// 測定で確定した印だけで列を判定する。1回の測定で両方のプレイヤーの列が揃った場合は、
+// 列の中で最も新しい手の番号が小さい（先に揃っていた）プレイヤーの勝ち
+func (quantumVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	var winnerID uint
+	var winLine *models.WinLine
+	best := 0
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		line := checkWin(game.Board, player.Symbol, game.Rules.WinLength)
+		if line == nil {
+			continue
+		}
+		latest := 0
+		for _, c := range line.Cells {
+			latest = max(latest, game.Quantum.Subscripts[c[0]][c[1]])
+		}
+		if winnerID == 0 || latest < best {
+			winnerID, winLine, best = player.ID, line, latest
+		}
+	}
+	if winnerID != 0 {
+		return winnerID, winLine, true
+	}
+	// 全てのセルが確定して勝者がいない場合は引き分け
+	return 0, nil, isBoardFull(game.Board)
+}
+
+// 2つのセルに重ね合わせの印を置く。確定していないセルが1つしか残っていない場合は、同じセルを2回指定して確定した印を置く
+func applyQuantumMove(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	variantRules := variantFor(game.Rules)
+	first, err := variantRules.locate(game, action)
+	if err != nil {
+		return nil, err
+	}
+	second, err := variantRules.locate(game, Action{X: action.X2, Y: action.Y2})
+	if err != nil {
+		return nil, err
+	}
+	if game.Board[first[0]][first[1]] != "" || game.Board[second[0]][second[1]] != "" {
+		return nil, ErrCellMarked
+	}
+	if game.CurrentTurn != action.PlayerID {
+		return nil, ErrNotYourTurn
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	if err := variantRules.checkMove(game, action.PlayerID, first[0], first[1]); err != nil {
+		return nil, err
+	}
+	if first == second && len(variantRules.candidates(game, action.PlayerID)) > 1 {
+		return nil, ErrInvalidCell
+	}
+
+	clocks := copyClocks(game.Clocks)
+	if !chargeClock(game, action.PlayerID, action.At) {
+		return flagPlayer(game, action.PlayerID), nil
+	}
+	recordMove(game, action.PlayerID, clocks)
+	return placeQuantumMark(game, action.PlayerID, first, second, action.At, randGen), nil
+}
+
+// 重ね合わせの印を置き、もつれが循環した場合は次の手番のプレイヤーに測定させるヘルパー関数
+func placeQuantumMark(game *models.Game, playerID uint, first, second [2]int, now time.Time, randGen *rand.Rand) []Event {
+	state := game.Quantum
+	state.MoveCount++
+	move := models.QuantumMove{
+		PlayerID: playerID,
+		Symbol:   playerSymbol(game, playerID),
+		Number:   state.MoveCount,
+		Cells:    [2][2]int{first, second},
+	}
+
+	// 最後の1セルには確定した印を置く
+	if first == second {
+		game.Board[first[0]][first[1]] = move.Symbol
+		state.Subscripts[first[0]][first[1]] = move.Number
+		return finishMove(game, first, now, randGen)
+	}
+
+	cycle := isEntangled(state.Moves, first, second)
+	state.Moves = append(state.Moves, move)
+	game.LastMove = nil // 確定した印が増えていないため、差分の描画はmovesを使う
+
+	events := refereeCountdown(game, randGen)
+	nextID := nextPlayerID(game, playerID)
+	StartTurn(game, nextID, now)
+	if cycle {
+		state.Collapse = &move
+		events = append(events, messageAll(fmt.Sprintf("SYSTEM: Entanglement cycle! %s must collapse it.", playerNickName(game, nextID))))
+	}
+	return append(events, Event{Type: EventGameState})
+}
+
+// 循環を作った手をどちらのセルに確定させるかを選んで測定する。審判が公平でない場合は、選んだのとは逆のセルに確定させることがある
+func applyCollapse(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	if game.Quantum == nil || game.Quantum.Collapse == nil {
+		return nil, ErrNoCollapse
+	}
+	if game.CurrentTurn != action.PlayerID {
+		return nil, ErrNotYourTurn
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+	move := *game.Quantum.Collapse
+	chosen := [2]int{action.X, action.Y}
+	if chosen != move.Cells[0] && chosen != move.Cells[1] {
+		return nil, ErrInvalidCell
+	}
+
+	if !chargeClock(game, action.PlayerID, action.At) {
+		return flagPlayer(game, action.PlayerID), nil
+	}
+
+	var events []Event
+	advantage := biasAdvantage(game, action.PlayerID)
+	if advantage <= 0 && !(advantage == 0 && randGen.Float64() < game.Rules.MarkAccuracy) {
+		// 審判が選択とは逆のセルに確定させる
+		chosen = otherCell(move, chosen)
+		events = append(events, messageAll("REFEREE: The measurement came out the other way!"))
+	}
+	events = append(events, measure(game, move, chosen)...)
+	// 測定したプレイヤーが続けて自分の手を打つため、手番の期限を改めて設定する
+	if game.Status == StatusInProgress {
+		StartTurn(game, game.CurrentTurn, action.At)
+	}
+	return events, nil
+}
+
+// 測定待ちのまま手番の期限が切れた場合に、審判がランダムに測定するヘルパー関数。
+// 測定後のプレイヤーの手には改めて制限時間を与える
+func timeoutCollapse(game *models.Game, now time.Time, randGen *rand.Rand) []Event {
+	move := *game.Quantum.Collapse
+	events := []Event{messageAll("SYSTEM: Time is up! The referee measured the entanglement at random.")}
+	events = append(events, measure(game, move, move.Cells[randGen.Intn(2)])...)
+	if game.Status == StatusInProgress {
+		StartTurn(game, game.CurrentTurn, now)
+	}
+	return events
+}
+
+// 手をセルに確定させ、同じセルに置かれていた他の印をもう一方のセルに連鎖的に確定させてから勝敗を判定するヘルパー関数。
+// 測定したプレイヤーは、ラウンドが続く場合はそのまま自分の手を打つ
+func measure(game *models.Game, start models.QuantumMove, cell [2]int) []Event {
+	state := game.Quantum
+	type assignment struct {
+		move models.QuantumMove
+		cell [2]int
+	}
+	resolved := make(map[int]bool)
+	queue := []assignment{{start, cell}}
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		if resolved[a.move.Number] {
+			continue
+		}
+		resolved[a.move.Number] = true
+		game.Board[a.cell[0]][a.cell[1]] = a.move.Symbol
+		state.Subscripts[a.cell[0]][a.cell[1]] = a.move.Number
+		for _, other := range state.Moves {
+			if !resolved[other.Number] && (other.Cells[0] == a.cell || other.Cells[1] == a.cell) {
+				queue = append(queue, assignment{other, otherCell(other, a.cell)})
+			}
+		}
+	}
+
+	remaining := make([]models.QuantumMove, 0, len(state.Moves))
+	for _, move := range state.Moves {
+		if !resolved[move.Number] {
+			remaining = append(remaining, move)
+		}
+	}
+	state.Moves = remaining
+	state.Collapse = nil
+
+	x, y, z := boardCoords(game.Rules, cell)
+	game.LastMove = &models.Move{PlayerID: start.PlayerID, X: x, Y: y, Z: z, Symbol: start.Symbol}
+
+	if events, done := judgeCell(game, cell, start.PlayerID); done {
+		return events
+	}
+	return []Event{{Type: EventGameState}}
+}
+
+// 手の2つのセルのうち、指定されたセルではない方を返すヘルパー関数
+func otherCell(move models.QuantumMove, cell [2]int) [2]int {
+	if move.Cells[0] == cell {
+		return move.Cells[1]
+	}
+	return move.Cells[0]
+}
+
+// もつれのグラフで2つのセルがすでにつながっているか（新しい手が循環を作るか）を判定するヘルパー関数
+func isEntangled(moves []models.QuantumMove, a, b [2]int) bool {
+	parent := make(map[[2]int][2]int)
+	var find func(cell [2]int) [2]int
+	find = func(cell [2]int) [2]int {
+		p, ok := parent[cell]
+		if !ok || p == cell {
+			return cell
+		}
+		root := find(p)
+		parent[cell] = root
+		return root
+	}
+	for _, move := range moves {
+		parent[find(move.Cells[0])] = find(move.Cells[1])
+	}
+	return find(a) == find(b)
+}
+
+// 量子三目並べの状態を複製するヘルパー関数
+func copyQuantum(state *models.QuantumState) *models.QuantumState {
+	if state == nil {
+		return nil
+	}
+	copied := *state
+	copied.Moves = append([]models.QuantumMove(nil), state.Moves...)
+	copied.Subscripts = make([][]int, len(state.Subscripts))
+	for i, row := range state.Subscripts {
+		copied.Subscripts[i] = append([]int(nil), row...)
+	}
+	if state.Collapse != nil {
+		collapse := *state.Collapse
+		copied.Collapse = &collapse
+	}
+	return &copied
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+// 量子三目並べのルールを返すヘルパー関数
+func quantumRules() models.GameRules {
+	rules := testRules()
+	rules.Variant = models.VariantQuantum
+	return rules
+}
+
+// 2つのセルに重ね合わせの印を置くアクションを返すヘルパー関数
+func quantumMark(playerID uint, first, second [2]int, at time.Time) Action {
+	return Action{Type: ActionMarkCell, PlayerID: playerID, X: first[0], Y: first[1], X2: second[0], Y2: second[1], At: at}
+}
+
+// もつれの手を返すヘルパー関数
+func quantumMove(playerID uint, symbol string, number int, first, second [2]int) models.QuantumMove {
+	return models.QuantumMove{PlayerID: playerID, Symbol: symbol, Number: number, Cells: [2][2]int{first, second}}
+}
+
+func TestIsEntangled(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []models.QuantumMove
+		a, b  [2]int
+		want  bool
+	}{
+		{name: "no moves", a: [2]int{0, 0}, b: [2]int{0, 1}},
+		{name: "same pair again", moves: []models.QuantumMove{quantumMove(testX, "X", 1, [2]int{0, 0}, [2]int{0, 1})}, a: [2]int{0, 1}, b: [2]int{0, 0}, want: true},
+		{
+			name: "connected through a chain",
+			moves: []models.QuantumMove{
+				quantumMove(testX, "X", 1, [2]int{0, 0}, [2]int{0, 1}),
+				quantumMove(testO, "O", 2, [2]int{0, 1}, [2]int{1, 1}),
+				quantumMove(testX, "X", 3, [2]int{1, 1}, [2]int{2, 2}),
+			},
+			a: [2]int{2, 2}, b: [2]int{0, 0}, want: true,
+		},
+		{
+			name: "separate components",
+			moves: []models.QuantumMove{
+				quantumMove(testX, "X", 1, [2]int{0, 0}, [2]int{0, 1}),
+				quantumMove(testO, "O", 2, [2]int{2, 1}, [2]int{2, 2}),
+			},
+			a: [2]int{0, 1}, b: [2]int{2, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEntangled(tt.moves, tt.a, tt.b); got != tt.want {
+				t.Errorf("isEntangled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	game := newTestGame(t, quantumRules())
+	cycle := quantumMove(testX, "X", 3, [2]int{1, 1}, [2]int{0, 0})
+	game.Quantum.Moves = []models.QuantumMove{
+		quantumMove(testX, "X", 1, [2]int{0, 0}, [2]int{0, 1}),
+		quantumMove(testO, "O", 2, [2]int{0, 1}, [2]int{1, 1}),
+		cycle,
+		quantumMove(testO, "O", 4, [2]int{2, 1}, [2]int{2, 2}),
+	}
+	game.Quantum.Collapse = &cycle
+
+	measure(game, cycle, [2]int{0, 0})
+
+	// 3手目が(0, 0)に確定すると、1手目は(0, 1)、2手目は(1, 1)に連鎖的に確定する
+	want := parseBoard("XX.", ".O.", "...")
+	if !reflect.DeepEqual(game.Board, want) {
+		t.Errorf("Board = %v, want %v", game.Board, want)
+	}
+	if got := game.Quantum.Subscripts[0][1]; got != 1 {
+		t.Errorf("Subscripts[0][1] = %d, want 1", got)
+	}
+	if len(game.Quantum.Moves) != 1 || game.Quantum.Moves[0].Number != 4 || game.Quantum.Collapse != nil {
+		t.Errorf("Moves = %+v, Collapse = %+v, want only move 4 left and no collapse", game.Quantum.Moves, game.Quantum.Collapse)
+	}
+}
+
+func TestMeasureTieBreak(t *testing.T) {
+	tests := []struct {
+		name        string
+		xSubscript  int
+		wantWinners []uint
+	}{
+		{name: "x completed its line first", xSubscript: 3, wantWinners: []uint{testX}},
+		{name: "o completed its line first", xSubscript: 8, wantWinners: []uint{testO}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestGame(t, quantumRules())
+			game.Board = parseBoard("XX.", "OO.", "...")
+			game.Quantum.Subscripts[0][0], game.Quantum.Subscripts[0][1] = 1, tt.xSubscript
+			game.Quantum.Subscripts[1][0], game.Quantum.Subscripts[1][1] = 2, 4
+			// 5手目を(0, 2)に確定させると、6手目が(1, 2)に確定し、両方の列が1回の測定で揃う
+			collapse := quantumMove(testX, "X", 5, [2]int{0, 2}, [2]int{2, 0})
+			game.Quantum.Moves = []models.QuantumMove{collapse, quantumMove(testO, "O", 6, [2]int{0, 2}, [2]int{1, 2})}
+			game.Quantum.Collapse = &collapse
+
+			measure(game, collapse, [2]int{0, 2})
+			if !slices.Equal(game.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", game.Winners, tt.wantWinners)
+			}
+		})
+	}
+}
+
+func TestCollapseRestartsTheTurn(t *testing.T) {
+	rules := quantumRules()
+	rules.TurnTimeLimit = 10
+	tests := []struct {
+		name   string
+		action Action
+	}{
+		{name: "collapse", action: Action{Type: ActionCollapse, PlayerID: testO, X: 0, Y: 0, At: testStart.Add(5 * time.Second)}},
+		{name: "timeout", action: Action{Type: ActionTimeout, At: testStart.Add(10 * time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randGen := rand.New(rand.NewSource(1))
+			// 2手目が1手目と同じ2つのセルを結び、もつれが循環する
+			game, _ := applyAll(t, newTestGame(t, rules), randGen,
+				quantumMark(testX, [2]int{0, 0}, [2]int{0, 1}, testStart),
+				quantumMark(testO, [2]int{0, 1}, [2]int{0, 0}, testStart),
+			)
+			if game.Quantum.Collapse == nil || game.CurrentTurn != testX {
+				t.Fatalf("Collapse = %+v, CurrentTurn = %d, want a collapse by %d", game.Quantum.Collapse, game.CurrentTurn, testX)
+			}
+			action := tt.action
+			action.PlayerID = game.CurrentTurn
+
+			next, _, err := Apply(game, action, randGen)
+			if err != nil {
+				t.Fatalf("Apply() returned error: %v", err)
+			}
+			if next.Quantum.Collapse != nil || countMarks(next.Board) != 2 {
+				t.Fatalf("Collapse = %+v, marks = %d, want the cycle measured", next.Quantum.Collapse, countMarks(next.Board))
+			}
+			if next.CurrentTurn != testX {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, testX)
+			}
+			if want := action.At.Add(10 * time.Second); !next.TurnDeadline.Equal(want) {
+				t.Errorf("TurnDeadline = %v, want %v", next.TurnDeadline, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// 禁じ手の判定で使う、列上のセルの状態
+const (
+	stoneEmpty   = iota // 空のセル
+	stoneOwn            // 判定するプレイヤーの印
+	stoneBlocked        // 相手の印または盤外
+)
+
+// ラウンドの先手のプレイヤーIDを返すヘルパー関数。
+// 最初の手の前に時間切れで手番が飛ばされても変わらないように、ラウンドの開始時に記録した値を使う
+func firstMover(game *models.Game) uint {
+	return game.FirstMover
+}
+
+// 連珠の禁じ手が適用されるプレイヤーかどうかを返すヘルパー関数
+func isRenjuRestricted(game *models.Game, playerID uint) bool {
+	return game.Rules.Renju && firstMover(game) == playerID
+}
+
+// プレイヤーが置いた印が勝利を決めたかを判定するヘルパー関数。
+// ExactLengthまたは連珠の先手の場合は、長連を勝利と認めない
+func winningLineAt(game *models.Game, cell [2]int, playerID uint, symbol string) *models.WinLine {
+	exact := game.Rules.ExactLength || isRenjuRestricted(game, playerID)
+	return checkWinAt(game.Board, cell[0], cell[1], symbol, game.Rules.WinLength, exact)
+}
+
+// 審判やタイムアウトが印を置く候補のセルから、プレイヤーの禁じ手を取り除くヘルパー関数
+func legalCells(game *models.Game, playerID uint, symbol string, cells [][2]int) [][2]int {
+	if !isRenjuRestricted(game, playerID) {
+		return cells
+	}
+	legal := make([][2]int, 0, len(cells))
+	for _, cell := range cells {
+		if !isForbiddenMove(game.Board, cell[0], cell[1], symbol, game.Rules.WinLength) {
+			legal = append(legal, cell)
+		}
+	}
+	return legal
+}
+
+// isForbiddenMove は(x, y)にsymbolを置く手が連珠の禁じ手（長連・四四・三三）かどうかを返す。
+// ちょうどwinLength個の列ができる手は、他の条件に関わらず禁じ手にならない。
+// 三の判定では、四を作る点自体が禁じ手かどうかまでは再帰的に調べない
+func isForbiddenMove(board [][]string, x, y int, symbol string, winLength int) bool {
+	overline := false
+	fours, threes := 0, 0
+	for _, d := range lineDirections {
+		line, center := lineStates(board, x, y, d.dx, d.dy, symbol, winLength+1)
+		start, end := runAround(line, center)
+		switch length := end - start + 1; {
+		case length == winLength:
+			return false
+		case length > winLength:
+			overline = true
+			continue
+		}
+
+		directionFours := countFours(line, center, winLength)
+		fours += directionFours
+		if directionFours == 0 && hasOpenThree(line, center, winLength) {
+			threes++
+		}
+	}
+	return overline || fours >= 2 || threes >= 2
+}
+
+// (x, y)を中心に、(dx, dy)の方向の前後reachセルの状態を返すヘルパー関数。中心には印が置かれたものとする
+func lineStates(board [][]string, x, y, dx, dy int, symbol string, reach int) ([]int, int) {
+	line := make([]int, 2*reach+1)
+	for i := -reach; i <= reach; i++ {
+		cx, cy := x+i*dx, y+i*dy
+		switch {
+		case i == 0:
+			line[reach+i] = stoneOwn
+		case !inBounds(board, cx, cy):
+			line[reach+i] = stoneBlocked
+		case board[cx][cy] == "":
+			line[reach+i] = stoneEmpty
+		case board[cx][cy] == symbol:
+			line[reach+i] = stoneOwn
+		default:
+			line[reach+i] = stoneBlocked
+		}
+	}
+	return line, reach
+}
+
+// line[i]を含む、自分の印が途切れずに続く範囲の両端のインデックスを返すヘルパー関数
+func runAround(line []int, i int) (int, int) {
+	start, end := i, i
+	for start > 0 && line[start-1] == stoneOwn {
+		start--
+	}
+	for end < len(line)-1 && line[end+1] == stoneOwn {
+		end++
+	}
+	return start, end
+}
+
+// 中心の印を含む四（あと1手でちょうどwinLength個になる形）の数を返すヘルパー関数。
+// 両端のどちらでも五になる活四は、同じ印の組なので1つと数える
+func countFours(line []int, center, winLength int) int {
+	seen := make(map[uint64]bool)
+	for p := range line {
+		if line[p] != stoneEmpty {
+			continue
+		}
+		line[p] = stoneOwn
+		start, end := runAround(line, p)
+		if end-start+1 == winLength && start <= center && center <= end {
+			var stones uint64
+			for i := start; i <= end; i++ {
+				if i != p {
+					stones |= 1 << i
+				}
+			}
+			seen[stones] = true
+		}
+		line[p] = stoneEmpty
+	}
+	return len(seen)
+}
+
+// 中心の印を含む活三（あと1手で両端が空いた活四になる形）があるかを返すヘルパー関数
+func hasOpenThree(line []int, center, winLength int) bool {
+	for p := range line {
+		if line[p] != stoneEmpty {
+			continue
+		}
+		line[p] = stoneOwn
+		open := isOpenFour(line, center, p, winLength)
+		line[p] = stoneEmpty
+		if open {
+			return true
+		}
+	}
+	return false
+}
+
+// 中心とpの印を含む列が、両端のどちらに置いてもちょうどwinLength個になる活四かどうかを返すヘルパー関数
+func isOpenFour(line []int, center, p, winLength int) bool {
+	start, end := runAround(line, center)
+	if end-start+1 != winLength-1 || p < start || p > end {
+		return false
+	}
+	if start < 1 || end > len(line)-2 || line[start-1] != stoneEmpty || line[end+1] != stoneEmpty {
+		return false
+	}
+	// 端に置いた結果が長連になる場合は活四とみなさない
+	if start >= 2 && line[start-2] == stoneOwn {
+		return false
+	}
+	if end <= len(line)-3 && line[end+2] == stoneOwn {
+		return false
+	}
+	return true
+}
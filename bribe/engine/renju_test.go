@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+func TestIsForbiddenMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells [][2]int
+		x, y  int
+		want  bool
+	}{
+		{name: "single open three", cells: [][2]int{{7, 5}, {7, 6}}, x: 7, y: 7},
+		{name: "double three", cells: [][2]int{{7, 5}, {7, 6}, {5, 7}, {6, 7}}, x: 7, y: 7, want: true},
+		{name: "double four", cells: [][2]int{{7, 4}, {7, 5}, {7, 6}, {4, 7}, {5, 7}, {6, 7}}, x: 7, y: 7, want: true},
+		{name: "three blocked on one side is not open", cells: [][2]int{{7, 0}, {7, 1}, {5, 2}, {6, 2}}, x: 7, y: 2},
+		{name: "overline", cells: [][2]int{{7, 1}, {7, 2}, {7, 3}, {7, 5}, {7, 6}}, x: 7, y: 4, want: true},
+		{name: "exact five wins over a double four", cells: [][2]int{{7, 3}, {7, 4}, {7, 5}, {7, 6}, {4, 7}, {5, 7}, {6, 7}, {4, 4}, {5, 5}, {6, 6}}, x: 7, y: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := parseBoard(gomokuRows(tt.cells...)...)
+			if got := isForbiddenMove(board, tt.x, tt.y, "X", 5); got != tt.want {
+				t.Errorf("isForbiddenMove() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenjuRestrictionFollowsTheFirstMover(t *testing.T) {
+	rules := testRules()
+	rules.BoardWidth, rules.BoardHeight, rules.WinLength = 15, 15, 5
+	rules.Renju = true
+	rules.TurnTimeLimit = 10
+	rules.TimeoutPenalty = models.TimeoutSkipTurn
+	game := newTestGame(t, rules)
+
+	// 最初の手の前に時間切れで手番が飛ばされても、禁じ手は先手のXに適用されたまま
+	next, _, err := Apply(game, Action{Type: ActionTimeout, At: testStart.Add(10 * time.Second)}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Apply() returned error: %v", err)
+	}
+	if next.CurrentTurn != testO {
+		t.Fatalf("CurrentTurn = %d, want %d", next.CurrentTurn, testO)
+	}
+	if !isRenjuRestricted(next, testX) || isRenjuRestricted(next, testO) {
+		t.Errorf("isRenjuRestricted(X) = %v, isRenjuRestricted(O) = %v, want true and false", isRenjuRestricted(next, testX), isRenjuRestricted(next, testO))
+	}
+}
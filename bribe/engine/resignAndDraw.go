@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"xicserver/models"
+)
+
+// 投了や引き分けの対象範囲
+const (
+	ScopeRound = "round" // 現在のラウンドのみ
+	ScopeMatch = "match" // マッチ全体
+)
+
+// DrawOfferTTL は引き分けの提案が有効な時間
+const DrawOfferTTL = 30 * time.Second
+
+// 投了したプレイヤーの負けとして、現在のラウンドまたはマッチ全体を終了する。
+// 3人以上の個人戦でラウンドを投了した場合は、そのプレイヤーだけがラウンドから抜け、残りのプレイヤーで続ける
+func applyResign(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	resignerIndex := playerIndex(game, action.PlayerID)
+	if resignerIndex == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	opponent := beneficiaryOf(game, action.PlayerID)
+	nickName := game.Players[resignerIndex].NickName
+
+	switch action.Scope {
+	case ScopeRound:
+		if game.Status != StatusInProgress {
+			return nil, ErrRoundNotInProgress
+		}
+		events := []Event{messageAll(fmt.Sprintf("SYSTEM: %s resigned this round.", nickName))}
+		if PlayerCount(game.Rules) > 2 && !game.Rules.Teams {
+			return resignFromRound(game, action, events, randGen)
+		}
+		return append(events, finishRound(game, opponent, nil)...), nil
+	case ScopeMatch, "":
+		if game.Status != StatusInProgress && game.Status != StatusRoundFinished {
+			return nil, ErrMatchNotInProgress
+		}
+		events := []Event{messageAll(fmt.Sprintf("SYSTEM: %s resigned the match.", nickName)), {Type: EventResults}}
+		return finishMatchWithWinner(game, opponent, FinishResigned, events), nil
+	default:
+		return nil, ErrInvalidScope
+	}
+}
+
+// 3人以上の個人戦で、投了したプレイヤーをラウンドから外すヘルパー関数。
+// 残りが1人になった場合はそのプレイヤーの勝利としてラウンドを終了し、投了したプレイヤーの手番だった場合は次のプレイヤーに渡す
+func resignFromRound(game *models.Game, action Action, events []Event, randGen *rand.Rand) ([]Event, error) {
+	if slices.Contains(game.RoundResigned, action.PlayerID) {
+		return nil, ErrAlreadyResigned
+	}
+	game.RoundResigned = append(game.RoundResigned, action.PlayerID)
+
+	var remaining []uint
+	for _, player := range game.Players {
+		if player != nil && !slices.Contains(game.RoundResigned, player.ID) {
+			remaining = append(remaining, player.ID)
+		}
+	}
+	if len(remaining) == 1 {
+		return append(events, finishRound(game, remaining[0], nil)...), nil
+	}
+
+	if game.Rules.Simultaneous {
+		// 提出済みのセルは取り消し、残りのプレイヤーの提出が揃っていれば公開する
+		delete(game.PendingMoves, action.PlayerID)
+		if len(WaitingFor(game)) == 0 {
+			return append(events, resolveMoves(game, action.At, randGen)...), nil
+		}
+	} else if game.CurrentTurn == action.PlayerID {
+		StartTurn(game, nextPlayerID(game, action.PlayerID), action.At)
+	}
+	return append(events, Event{Type: EventGameState}), nil
+}
+
+// 他のプレイヤーに引き分けを提案する
+func applyOfferDraw(game *models.Game, action Action) ([]Event, error) {
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+
+	scope := action.Scope
+	if scope == "" {
+		scope = ScopeRound // 引き分けの提案は特に指定がなければ現在のラウンドが対象
+	}
+	if scope != ScopeRound && scope != ScopeMatch {
+		return nil, ErrInvalidScope
+	}
+
+	// 有効な提案が残っている間は新たに提案できない
+	if game.DrawOffer != nil && action.At.Before(game.DrawOffer.ExpiresAt) {
+		return nil, ErrDrawOfferPending
+	}
+
+	game.DrawOffer = &models.DrawOffer{
+		From:      action.PlayerID,
+		Scope:     scope,
+		ExpiresAt: action.At.Add(DrawOfferTTL),
+	}
+	var events []Event
+	for _, player := range game.Players {
+		if player != nil && player.ID != action.PlayerID {
+			events = append(events, messageTo(player.ID, "SYSTEM: Your opponent offers a draw!"))
+		}
+	}
+	return append(events, Event{Type: EventGameState}), nil
+}
+
+// 対戦相手からの引き分けの提案を受諾または拒否する。3人以上の対戦では提案者以外の全員の受諾で成立する
+func applyRespondDraw(game *models.Game, action Action) ([]Event, error) {
+	offer := game.DrawOffer
+	if offer == nil || offer.From == action.PlayerID || playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrNoDrawOffer
+	}
+	// 期限切れの提案は、取り消したことを通知して状態に反映する
+	if !action.At.Before(offer.ExpiresAt) {
+		game.DrawOffer = nil
+		return []Event{
+			messageTo(action.PlayerID, "SYSTEM: The draw offer has expired."),
+			{Type: EventGameState},
+		}, nil
+	}
+
+	if !action.Accept {
+		game.DrawOffer = nil
+		return []Event{
+			messageTo(offer.From, "SYSTEM: Your draw offer was declined."),
+			{Type: EventGameState},
+		}, nil
+	}
+
+	if !slices.Contains(offer.AcceptedBy, action.PlayerID) {
+		offer.AcceptedBy = append(offer.AcceptedBy, action.PlayerID)
+	}
+	// 提案者以外のプレイヤーが全員受諾するまで待つ
+	for _, player := range game.Players {
+		if player != nil && player.ID != offer.From && !slices.Contains(offer.AcceptedBy, player.ID) {
+			return []Event{{Type: EventGameState}}, nil
+		}
+	}
+	game.DrawOffer = nil
+
+	events := []Event{messageAll("SYSTEM: Draw agreed.")}
+	if offer.Scope == ScopeMatch {
+		events = append(events, Event{Type: EventResults})
+		return finishMatchWithWinner(game, 0, FinishDrawAgreed, events), nil
+	}
+	return append(events, finishRound(game, 0, nil)...), nil
+}
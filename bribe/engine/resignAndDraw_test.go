@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// ラウンドを投了するアクションを返すヘルパー関数
+func resignRound(playerID uint) Action {
+	return Action{Type: ActionResign, PlayerID: playerID, Scope: ScopeRound, At: testStart}
+}
+
+func TestApplyResign(t *testing.T) {
+	tests := []struct {
+		name            string
+		setup           []Action
+		action          Action
+		wantErr         error
+		wantStatus      string
+		wantWinners     []uint
+		wantMatchWinner uint
+		wantFinished    string
+	}{
+		{name: "round resign gives the round to the opponent", action: resignRound(testX), wantStatus: StatusRoundFinished, wantWinners: []uint{testO}},
+		{name: "match resign ends the match", action: Action{Type: ActionResign, PlayerID: testO, Scope: ScopeMatch, At: testStart}, wantStatus: StatusFinished, wantMatchWinner: testX, wantFinished: FinishResigned},
+		{name: "round resign between rounds", setup: []Action{resignRound(testX)}, action: resignRound(testO), wantErr: ErrRoundNotInProgress},
+		{name: "unknown scope", action: Action{Type: ActionResign, PlayerID: testX, Scope: "set", At: testStart}, wantErr: ErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, testRules()), randGen, tt.setup...)
+
+			next, _, err := Apply(game, tt.action, randGen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if next.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if !slices.Equal(next.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", next.Winners, tt.wantWinners)
+			}
+			if next.MatchWinner != tt.wantMatchWinner || next.FinishReason != tt.wantFinished {
+				t.Errorf("MatchWinner = %d, FinishReason = %q, want %d and %q", next.MatchWinner, next.FinishReason, tt.wantMatchWinner, tt.wantFinished)
+			}
+		})
+	}
+}
+
+func TestApplyResignRound(t *testing.T) {
+	const testDelta uint = 3
+	tests := []struct {
+		name         string
+		players      int
+		setup        []Action
+		action       Action
+		wantErr      error
+		wantStatus   string
+		wantTurn     uint
+		wantWinners  []uint
+		wantResigned []uint
+	}{
+		{name: "two players: the opponent wins the round", players: 2, action: resignRound(testX), wantStatus: StatusRoundFinished, wantWinners: []uint{testO}},
+		{name: "three players: the turn passes on", players: 3, action: resignRound(testX), wantStatus: StatusInProgress, wantTurn: testO, wantResigned: []uint{testX}},
+		{name: "three players: resigning off turn keeps the turn", players: 3, action: resignRound(testO), wantStatus: StatusInProgress, wantTurn: testX, wantResigned: []uint{testO}},
+		{name: "three players: the resigned player is skipped", players: 3, setup: []Action{resignRound(testX), mark(testO, 0, 0)}, action: mark(testDelta, 1, 1), wantStatus: StatusInProgress, wantTurn: testO, wantResigned: []uint{testX}},
+		{name: "three players: the last player left wins the round", players: 3, setup: []Action{resignRound(testX)}, action: resignRound(testO), wantStatus: StatusRoundFinished, wantWinners: []uint{testDelta}},
+		{name: "three players: resigning twice", players: 3, setup: []Action{resignRound(testX)}, action: resignRound(testX), wantErr: ErrAlreadyResigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.Players = tt.players
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, rules), randGen, tt.setup...)
+
+			next, _, err := Apply(game, tt.action, randGen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if next.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if !slices.Equal(next.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", next.Winners, tt.wantWinners)
+			}
+			if tt.wantStatus != StatusInProgress {
+				return
+			}
+			if next.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, tt.wantTurn)
+			}
+			if !slices.Equal(next.RoundResigned, tt.wantResigned) {
+				t.Errorf("RoundResigned = %v, want %v", next.RoundResigned, tt.wantResigned)
+			}
+		})
+	}
+}
+
+func TestApplyRespondDraw(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      bool
+		after       time.Duration
+		wantStatus  string
+		wantWinners []uint
+		wantMessage string
+	}{
+		{name: "accepted", accept: true, after: time.Second, wantStatus: StatusRoundFinished, wantWinners: []uint{0}},
+		{name: "declined", accept: false, after: time.Second, wantStatus: StatusInProgress, wantMessage: "SYSTEM: Your draw offer was declined."},
+		{name: "expired", accept: true, after: DrawOfferTTL, wantStatus: StatusInProgress, wantMessage: "SYSTEM: The draw offer has expired."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, testRules()), randGen,
+				Action{Type: ActionOfferDraw, PlayerID: testX, Scope: ScopeRound, At: testStart},
+			)
+
+			next, events, err := Apply(game, Action{Type: ActionRespondDraw, PlayerID: testO, Accept: tt.accept, At: testStart.Add(tt.after)}, randGen)
+			if err != nil {
+				t.Fatalf("Apply() returned error: %v", err)
+			}
+			if next.DrawOffer != nil {
+				t.Errorf("DrawOffer = %+v, want nil", next.DrawOffer)
+			}
+			if next.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if !slices.Equal(next.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", next.Winners, tt.wantWinners)
+			}
+			if tt.wantMessage != "" && !slices.ContainsFunc(events, func(event Event) bool { return event.Message == tt.wantMessage }) {
+				t.Errorf("events = %+v, want message %q", events, tt.wantMessage)
+			}
+		})
+	}
+}
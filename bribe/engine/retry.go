@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"math/rand"
+
+	"xicserver/models"
+)
+
+func applyRetry(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	// すでに終了したゲームではない、または再戦リクエストを受け付ける状態でない場合は受け付けない
+	if game.Status != StatusRoundFinished {
+		return nil, ErrRetryNotApplicable
+	}
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+
+	if game.RetryRequests == nil {
+		game.RetryRequests = make(map[uint]bool)
+	}
+	game.RetryRequests[action.PlayerID] = action.WantRetry
+
+	// 再戦を望まない場合、ゲームを直ちに終了させる
+	if !action.WantRetry {
+		return finishMatch(game, []Event{{Type: EventGameState}}), nil
+	}
+
+	// 再戦を望む場合、他のプレイヤーに通知する
+	var events []Event
+	for _, player := range game.Players {
+		if player != nil && player.ID != action.PlayerID {
+			events = append(events, Event{Type: EventRetryRequested, To: player.ID})
+		}
+	}
+
+	// 全てのプレイヤーからの再戦リクエストを確認
+	for _, player := range game.Players {
+		if player == nil || !game.RetryRequests[player.ID] {
+			return events, nil
+		}
+	}
+	// OrderとChaosでは先手がOrderとなるため、ラウンドごとに先手を交代して役割を入れ替える
+	nextTurn := game.CurrentTurn
+	if game.Rules.Variant == models.VariantOrderChaos {
+		nextTurn = opponentID(game, firstMover(game))
+	}
+	game.Round++
+	game.Status = StatusInProgress
+	if err := resetGameForNextRound(game, randGen); err != nil {
+		events = append(events, messageAll("SYSTEM: No obstacle layout was found, so this round has no obstacles."))
+	}
+	game.FirstMover = nextTurn
+	StartTurn(game, nextTurn, action.At)
+	return append(events, Event{Type: EventGameState}), nil
+}
+
+// ゲームを次のラウンドに向けてリセットするヘルパー関数。障害物を配置できなかった場合はResetBoardのエラーを返す
+func resetGameForNextRound(game *models.Game, randGen *rand.Rand) error {
+	// ボードとバリエーション固有の状態のリセット
+	err := ResetBoard(game, randGen)
+
+	// その他の状態のリセット
+	game.BribeCounts = make([]int, len(game.Players))
+	game.BiasDegree = 0
+	game.FavoredPlayer = 0
+	game.RefereeStatus = RandomNormalRefereeStatus(randGen)
+	game.RefereeCount = 0
+	game.RetryRequests = nil // 次のラウンドの再戦リクエストと混ざらないようにする
+	game.History = nil
+	game.LastMove = nil
+	game.LastMoves = nil
+	game.PendingMoves = nil
+	return err
+}
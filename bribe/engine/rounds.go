@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// マッチの終了理由
+const (
+	FinishFlagged    = "flagged"    // 持ち時間切れ
+	FinishResigned   = "resigned"   // 投了
+	FinishDrawAgreed = "drawAgreed" // 合意による引き分け
+)
+
+// ゲームの進行状態
+const (
+	StatusInProgress    = "in_progress"    // ラウンドが進行中
+	StatusRoundFinished = "round_finished" // ラウンドが終了し、再戦リクエストを待っている
+	StatusFinished      = "finished"       // マッチ全体が終了
+)
+
+// ラウンドの勝者（引き分けは0）と勝利した列を記録し、マッチの決着を判定してステータスを更新するヘルパー関数。
+// 投了や時間切れなど、列が揃わずに決着した場合のwinLineはnil
+func finishRound(game *models.Game, winnerID uint, winLine *models.WinLine) []Event {
+	if winnerID != 0 {
+		winnerID = teamLeader(game, winnerID) // チーム戦ではチームの勝利として記録する
+	}
+	game.Winners = append(game.Winners, winnerID)
+	game.WinningLines = append(game.WinningLines, winLine)
+	game.DrawOffer = nil
+	game.UndoRequest = nil
+	stopTurn(game) // ラウンドが終わったので手番の制限時間と持ち時間を止める
+	if !isMatchDecided(game) {
+		game.Status = StatusRoundFinished
+		return []Event{{Type: EventResults}}
+	}
+	return finishMatch(game, []Event{{Type: EventResults}})
+}
+
+// マッチを終了させて勝者を確定し、ゲーム終了のイベントを追加するヘルパー関数
+func finishMatch(game *models.Game, events []Event) []Event {
+	return finishMatchWithWinner(game, matchLeader(game), "", events)
+}
+
+// ラウンドの勝敗以外の理由で勝者が決まった場合に、マッチを終了させるヘルパー関数
+func finishMatchWithWinner(game *models.Game, winnerID uint, reason string, events []Event) []Event {
+	stopTurn(game)
+	game.DrawOffer = nil
+	game.UndoRequest = nil
+	game.Status = StatusFinished
+	game.MatchWinner = winnerID
+	if winnerID != 0 {
+		game.MatchWinner = teamLeader(game, winnerID)
+	}
+	game.FinishReason = reason
+	return append(events, Event{Type: EventGameFinished})
+}
+
+// プレイヤーごとのラウンド勝利数を返すヘルパー関数
+func countRoundWins(game *models.Game) map[uint]int {
+	wins := make(map[uint]int)
+	for _, winnerID := range game.Winners {
+		if winnerID != 0 {
+			wins[winnerID]++
+		}
+	}
+	return wins
+}
+
+// マッチの決着がついたかどうかを判定するヘルパー関数
+func isMatchDecided(game *models.Game) bool {
+	played := len(game.Winners)
+	if played >= game.Rules.Rounds {
+		return true
+	}
+
+	wins := countRoundWins(game)
+	first, second := 0, 0
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		if w := wins[player.ID]; w > first {
+			first, second = w, first
+		} else if w > second {
+			second = w
+		}
+	}
+
+	switch game.Rules.MatchFormat {
+	case models.MatchFirstTo:
+		return first >= game.Rules.TargetWins
+	default:
+		// 残りのラウンドを全て取っても追いつけなければ決着
+		return first-second > game.Rules.Rounds-played
+	}
+}
+
+// ラウンド勝利数が単独で最も多いプレイヤーのIDを返すヘルパー関数。並んでいる場合は0
+func matchLeader(game *models.Game) uint {
+	wins := countRoundWins(game)
+	var leader uint
+	best, tied := 0, false
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		if w := wins[player.ID]; w > best {
+			leader, best, tied = player.ID, w, false
+		} else if w == best {
+			tied = true
+		}
+	}
+	if tied || best == 0 {
+		return 0
+	}
+	return leader
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestIsMatchDecided(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		rounds      int
+		targetWins  int
+		winners     []uint
+		wantDecided bool
+		wantLeader  uint
+	}{
+		{name: "best of three after one win", format: models.MatchBestOf, rounds: 3, targetWins: 2, winners: []uint{testX}, wantLeader: testX},
+		{name: "best of three after two wins", format: models.MatchBestOf, rounds: 3, targetWins: 2, winners: []uint{testX, testX}, wantDecided: true, wantLeader: testX},
+		{name: "best of three split after two rounds", format: models.MatchBestOf, rounds: 3, targetWins: 2, winners: []uint{testX, testO}},
+		{name: "best of three with a draw", format: models.MatchBestOf, rounds: 3, targetWins: 2, winners: []uint{0, testO}, wantLeader: testO},
+		{name: "best of three ends after the last round", format: models.MatchBestOf, rounds: 3, targetWins: 2, winners: []uint{testX, testO, 0}, wantDecided: true},
+		{name: "first to two is not decided by a lead", format: models.MatchFirstTo, rounds: 5, targetWins: 2, winners: []uint{testX, 0, 0}, wantLeader: testX},
+		{name: "first to two after two wins", format: models.MatchFirstTo, rounds: 5, targetWins: 2, winners: []uint{testX, testO, testO}, wantDecided: true, wantLeader: testO},
+		{name: "first to three stops at the round limit", format: models.MatchFirstTo, rounds: 3, targetWins: 3, winners: []uint{testX, testX, testO}, wantDecided: true, wantLeader: testX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.MatchFormat, rules.Rounds, rules.TargetWins = tt.format, tt.rounds, tt.targetWins
+			game := newTestGame(t, rules)
+			game.Winners = tt.winners
+
+			if got := isMatchDecided(game); got != tt.wantDecided {
+				t.Errorf("isMatchDecided() = %v, want %v", got, tt.wantDecided)
+			}
+			if got := matchLeader(game); got != tt.wantLeader {
+				t.Errorf("matchLeader() = %d, want %d", got, tt.wantLeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"xicserver/models"
+)
+
+// 同時手番のルールでは、各プレイヤーが手番ごとに1つのセルを非公開で提出し、全員の提出が揃った時点で
+// まとめて公開して盤面に置く。同じセルが選ばれた場合は審判がどちらに置くかを決める。
+// 手番は全員のものとなるため、CurrentTurnは0のまま進行する
+
+// 選択されたセルを提出し、全員の提出が揃ったら公開して盤面に置く
+func applyCommitMove(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	selected, err := variantFor(game.Rules).locate(game, action)
+	if err != nil {
+		return nil, err
+	}
+	if game.Board[selected[0]][selected[1]] == ObstacleMark {
+		return nil, ErrCellBlocked
+	}
+	if game.Board[selected[0]][selected[1]] != "" {
+		return nil, ErrCellMarked
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	// 全員が揃ってマッチが始まるまでは提出できない
+	if slices.Contains(game.Players, nil) {
+		return nil, ErrNotYourTurn
+	}
+	if _, ok := game.PendingMoves[action.PlayerID]; ok {
+		return nil, ErrMoveCommitted
+	}
+	if slices.Contains(game.RoundResigned, action.PlayerID) {
+		return nil, ErrAlreadyResigned
+	}
+
+	if game.PendingMoves == nil {
+		game.PendingMoves = make(map[uint][2]int)
+	}
+	game.PendingMoves[action.PlayerID] = selected
+	if len(WaitingFor(game)) > 0 {
+		return []Event{{Type: EventWaitingForMoves}}, nil
+	}
+	return resolveMoves(game, action.At, randGen), nil
+}
+
+// WaitingFor は同時手番で、現在の手番にまだセルを提出していないプレイヤーのIDを返す
+func WaitingFor(game *models.Game) []uint {
+	var waiting []uint
+	for _, player := range game.Players {
+		if player == nil || slices.Contains(game.RoundResigned, player.ID) {
+			continue
+		}
+		if _, ok := game.PendingMoves[player.ID]; !ok {
+			waiting = append(waiting, player.ID)
+		}
+	}
+	return waiting
+}
+
+// 提出されたセルを公開して盤面に置き、勝敗を判定するヘルパー関数。
+// 提出しなかったプレイヤー（時間切れで手番を飛ばされた場合）の印は置かれない
+func resolveMoves(game *models.Game, now time.Time, randGen *rand.Rand) []Event {
+	variantRules := variantFor(game.Rules)
+	recordMove(game, 0, copyClocks(game.Clocks))
+
+	var events []Event
+	var placed []models.Move
+	var cells [][2]int
+	claimed := make(map[[2]int]uint) // 選択されたセルごとに、審判が印を置くことにしたプレイヤー
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		selected, ok := game.PendingMoves[player.ID]
+		if !ok {
+			continue
+		}
+		if rivalID, taken := claimed[selected]; taken {
+			// 同じセルが選ばれた場合は、審判が有利に扱うプレイヤーに置く。審判が公平なら五分五分
+			if winner := collisionWinner(game, rivalID, player.ID, randGen); winner == player.ID {
+				claimed[selected] = player.ID
+			}
+			events = append(events, messageAll(fmt.Sprintf("REFEREE: Both chose the same cell! %s takes it.", playerNickName(game, claimed[selected]))))
+			continue
+		}
+		claimed[selected] = player.ID
+	}
+
+	for _, player := range game.Players {
+		if player == nil {
+			continue
+		}
+		selected, ok := game.PendingMoves[player.ID]
+		if !ok || claimed[selected] != player.ID {
+			continue
+		}
+		// 通常の手番と同じく、審判が選択どおりに置かない場合は他の空のセルに置く
+		cell := selected
+		advantage := biasAdvantage(game, player.ID)
+		if game.Board[cell[0]][cell[1]] != "" || (advantage <= 0 && !(advantage == 0 && randGen.Float64() < game.Rules.MarkAccuracy)) {
+			if emptyCells := variantRules.alternatives(game, player.ID, selected); len(emptyCells) > 0 {
+				cell = emptyCells[randGen.Intn(len(emptyCells))]
+			}
+		}
+		if game.Board[cell[0]][cell[1]] != "" {
+			continue // 先に置かれた印で空きがなくなった場合
+		}
+		game.Board[cell[0]][cell[1]] = player.Symbol
+		x, y, z := boardCoords(game.Rules, cell)
+		placed = append(placed, models.Move{PlayerID: player.ID, X: x, Y: y, Z: z, Symbol: player.Symbol})
+		cells = append(cells, cell)
+	}
+	game.PendingMoves = nil
+	game.LastMoves = placed
+	if len(placed) > 0 {
+		game.LastMove = &placed[len(placed)-1]
+	}
+	events = append(events, refereeCountdown(game, randGen)...)
+
+	// 置かれた全ての印について勝敗を判定する。両方のプレイヤーが同時に列を揃えた場合は引き分け
+	var winners []uint
+	var winLine *models.WinLine
+	full := false
+	for i, cell := range cells {
+		winnerID, line, done := variantRules.judge(game, cell, placed[i].PlayerID, placed[i].Symbol)
+		if !done {
+			continue
+		}
+		if winnerID == 0 {
+			full = true
+			continue
+		}
+		if game.Rules.Misere {
+			winnerID = opponentID(game, winnerID)
+		}
+		if !slices.Contains(winners, winnerID) {
+			winners = append(winners, winnerID)
+			winLine = line
+		}
+	}
+	switch {
+	case len(winners) == 1:
+		winLine.MoveNumber = len(game.History)
+		return append(events, finishRound(game, winners[0], winLine)...)
+	case len(winners) > 1:
+		events = append(events, messageAll("REFEREE: Both completed a line at once! It's a draw."))
+		return append(events, finishRound(game, 0, nil)...)
+	case full || isBoardFull(game.Board):
+		return append(events, finishRound(game, 0, nil)...)
+	}
+
+	StartTurn(game, 0, now)
+	return append(events, Event{Type: EventGameState})
+}
+
+// 同じセルを選んだ2人のプレイヤーのうち、審判が印を置くプレイヤーを決めるヘルパー関数
+func collisionWinner(game *models.Game, firstID, secondID uint, randGen *rand.Rand) uint {
+	first, second := biasAdvantage(game, firstID), biasAdvantage(game, secondID)
+	switch {
+	case first > second:
+		return firstID
+	case second > first:
+		return secondID
+	case randGen.Intn(2) == 0:
+		return firstID
+	default:
+		return secondID
+	}
+}
+
+// 同時手番で手番の期限が切れた場合に、まだ提出していないプレイヤーにルールで決められたペナルティを適用する
+func applySimultaneousTimeout(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	waiting := WaitingFor(game)
+	if len(waiting) == 0 {
+		return nil, ErrTimerNotExpired
+	}
+
+	switch game.Rules.TimeoutPenalty {
+	case models.TimeoutSkipTurn:
+		// 提出しなかったプレイヤーの印は置かない
+		events := []Event{messageAll("SYSTEM: Time is up! Missing moves have been skipped.")}
+		return append(events, resolveMoves(game, action.At, randGen)...), nil
+	case models.TimeoutForfeitRound:
+		// 提出しなかったプレイヤーが1人なら相手の勝利、全員なら引き分けとしてラウンドを終了する
+		events := []Event{messageAll("SYSTEM: Time is up! The round has been forfeited.")}
+		var winnerID uint
+		if len(waiting) == 1 {
+			winnerID = beneficiaryOf(game, waiting[0])
+		}
+		game.PendingMoves = nil
+		return append(events, finishRound(game, winnerID, nil)...), nil
+	default:
+		// 提出しなかったプレイヤーのセルをランダムに選ぶ
+		events := []Event{messageAll("SYSTEM: Time is up! Missing moves have been chosen at random.")}
+		if game.PendingMoves == nil {
+			game.PendingMoves = make(map[uint][2]int)
+		}
+		for _, playerID := range waiting {
+			emptyCells := variantFor(game.Rules).candidates(game, playerID)
+			if len(emptyCells) == 0 {
+				// 盤面が埋まった時点でラウンドは終了しているため、通常は到達しない
+				return nil, ErrTimerNotExpired
+			}
+			game.PendingMoves[playerID] = emptyCells[randGen.Intn(len(emptyCells))]
+		}
+		return append(events, resolveMoves(game, action.At, randGen)...), nil
+	}
+}
+
+// プレイヤーのニックネームを返すヘルパー関数。見つからない場合は空文字列
+func playerNickName(game *models.Game, playerID uint) string {
+	if i := playerIndex(game, playerID); i != -1 {
+		return game.Players[i].NickName
+	}
+	return ""
+}
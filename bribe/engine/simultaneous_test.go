@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+func TestApplySimultaneous(t *testing.T) {
+	bribe := Action{Type: ActionBribe, PlayerID: testO, At: testStart}
+	timeout := Action{Type: ActionTimeout, At: testStart.Add(10 * time.Second)}
+	tests := []struct {
+		name        string
+		penalty     string
+		actions     []Action
+		action      Action
+		wantErr     error
+		wantStatus  string
+		wantMarks   map[[2]int]string // 盤面に置かれているべき印。nilなら印の数だけを調べる
+		wantCount   int
+		wantPending []uint
+		wantWinners []uint
+	}{
+		{
+			name:        "first commit stays hidden",
+			action:      mark(testX, 0, 0),
+			wantStatus:  StatusInProgress,
+			wantMarks:   map[[2]int]string{},
+			wantPending: []uint{testX},
+		},
+		{
+			name:       "both commits are revealed together",
+			actions:    []Action{mark(testX, 0, 0)},
+			action:     mark(testO, 1, 1),
+			wantStatus: StatusInProgress,
+			wantMarks:  map[[2]int]string{{0, 0}: "X", {1, 1}: "O"},
+		},
+		{
+			name:    "committing twice",
+			actions: []Action{mark(testX, 0, 0)},
+			action:  mark(testX, 1, 1),
+			wantErr: ErrMoveCommitted,
+		},
+		{
+			// 審判は賄賂を贈ったOに傾いているため、同じセルはOのものになり、Xの印は置かれない
+			name:       "collision settled by the biased referee",
+			actions:    []Action{bribe, mark(testX, 1, 1)},
+			action:     mark(testO, 1, 1),
+			wantStatus: StatusInProgress,
+			wantMarks:  map[[2]int]string{{1, 1}: "O"},
+		},
+		{
+			// 公平な審判はどちらか一方に置く
+			name:       "collision with a neutral referee",
+			actions:    []Action{mark(testX, 1, 1)},
+			action:     mark(testO, 1, 1),
+			wantStatus: StatusInProgress,
+			wantCount:  1,
+		},
+		{
+			name:       "timeout skips the missing move",
+			penalty:    models.TimeoutSkipTurn,
+			actions:    []Action{mark(testX, 0, 0)},
+			action:     timeout,
+			wantStatus: StatusInProgress,
+			wantMarks:  map[[2]int]string{{0, 0}: "X"},
+		},
+		{
+			name:       "timeout chooses the missing move at random",
+			penalty:    models.TimeoutRandomMove,
+			actions:    []Action{mark(testX, 0, 0)},
+			action:     timeout,
+			wantStatus: StatusInProgress,
+			wantCount:  2,
+		},
+		{
+			name:        "timeout forfeits the round of the missing player",
+			penalty:     models.TimeoutForfeitRound,
+			actions:     []Action{mark(testX, 0, 0)},
+			action:      timeout,
+			wantStatus:  StatusRoundFinished,
+			wantMarks:   map[[2]int]string{},
+			wantWinners: []uint{testX},
+		},
+		{
+			name:       "timeout before anyone committed",
+			penalty:    models.TimeoutSkipTurn,
+			action:     timeout,
+			wantStatus: StatusInProgress,
+			wantMarks:  map[[2]int]string{},
+		},
+		{
+			name:    "timeout before the deadline",
+			penalty: models.TimeoutSkipTurn,
+			actions: []Action{mark(testX, 0, 0)},
+			action:  Action{Type: ActionTimeout, At: testStart.Add(9 * time.Second)},
+			wantErr: ErrTimerNotExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.Simultaneous = true
+			if tt.penalty != "" {
+				rules.TurnTimeLimit = 10
+				rules.TimeoutPenalty = tt.penalty
+			}
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, rules), randGen, tt.actions...)
+
+			next, _, err := Apply(game, tt.action, randGen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if next.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if tt.wantMarks != nil {
+				for x, row := range next.Board {
+					for y, cell := range row {
+						if cell != tt.wantMarks[[2]int{x, y}] {
+							t.Errorf("Board[%d][%d] = %q, want %q", x, y, cell, tt.wantMarks[[2]int{x, y}])
+						}
+					}
+				}
+			} else if got := countMarks(next.Board); got != tt.wantCount {
+				t.Errorf("board has %d marks, want %d", got, tt.wantCount)
+			}
+			var pending []uint
+			for playerID := range next.PendingMoves {
+				pending = append(pending, playerID)
+			}
+			if !slices.Equal(pending, tt.wantPending) {
+				t.Errorf("PendingMoves from %v, want %v", pending, tt.wantPending)
+			}
+			if !slices.Equal(next.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", next.Winners, tt.wantWinners)
+			}
+			if next.CurrentTurn != 0 {
+				t.Errorf("CurrentTurn = %d, want 0 in simultaneous mode", next.CurrentTurn)
+			}
+		})
+	}
+}
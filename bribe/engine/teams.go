@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// 2対2のチーム戦の人数
+const TeamPlayers = 4
+
+// チーム戦では席の偶数番と奇数番がそれぞれ1つのチームとなり、席順に手番が回るためチームが交互に手番を持つ。
+// 勝者や審判の有利不利、賄賂の回数はチームの代表（Players[0]またはPlayers[1]）のIDでまとめて記録する
+
+// SeatSymbol はルールと席の番号から、その席のプレイヤーに割り当てるシンボルを返す
+func SeatSymbol(rules models.GameRules, seat int) string {
+	if rules.Teams {
+		return PlayerSymbols[seat%2]
+	}
+	return PlayerSymbols[seat]
+}
+
+// TeamOf はプレイヤーのチームの番号（0または1）を返す。チーム戦でない場合や見つからない場合は-1
+func TeamOf(game *models.Game, playerID uint) int {
+	if !game.Rules.Teams {
+		return -1
+	}
+	if i := playerIndex(game, playerID); i != -1 {
+		return i % 2
+	}
+	return -1
+}
+
+// プレイヤーが属するチームの代表のIDを返すヘルパー関数。チーム戦でない場合はプレイヤー自身のID
+func teamLeader(game *models.Game, playerID uint) uint {
+	team := TeamOf(game, playerID)
+	if team == -1 || game.Players[team] == nil {
+		return playerID
+	}
+	return game.Players[team].ID
+}
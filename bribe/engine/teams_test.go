@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestTeamTurnOrder(t *testing.T) {
+	// 席順にX、O、X、Oとなり、1と3、2と4がそれぞれ同じチーム
+	const seat3, seat4 uint = 3, 4
+	bribe := func(playerID uint) Action {
+		return Action{Type: ActionBribe, PlayerID: playerID, At: testStart}
+	}
+	tests := []struct {
+		name        string
+		actions     []Action
+		wantStatus  string
+		wantTurn    uint
+		wantWinners []uint
+		wantBribes  []int
+		wantFavored uint
+	}{
+		{name: "teams alternate through the seats", actions: []Action{mark(testX, 0, 0), mark(testO, 1, 1)}, wantStatus: StatusInProgress, wantTurn: seat3, wantBribes: []int{0, 0, 0, 0}},
+		{
+			name:       "turn returns to the first seat",
+			actions:    []Action{mark(testX, 0, 0), mark(testO, 1, 1), mark(seat3, 2, 2), mark(seat4, 0, 2)},
+			wantStatus: StatusInProgress,
+			wantTurn:   testX,
+			wantBribes: []int{0, 0, 0, 0},
+		},
+		{
+			// 味方の印と合わせて列を揃えると、チームの代表の勝利として記録される
+			name:        "a teammate completes the line",
+			actions:     []Action{mark(testX, 0, 0), mark(testO, 1, 0), mark(seat3, 0, 1), mark(seat4, 1, 1), mark(testX, 2, 2), mark(testO, 2, 0), mark(seat3, 0, 2)},
+			wantStatus:  StatusRoundFinished,
+			wantWinners: []uint{testX},
+			wantBribes:  []int{0, 0, 0, 0},
+		},
+		{name: "a teammate's bribe counts for the team", actions: []Action{bribe(seat3)}, wantStatus: StatusInProgress, wantTurn: testX, wantBribes: []int{1, 0, 0, 0}, wantFavored: testX},
+		{name: "bribes cancel across teams", actions: []Action{bribe(seat3), bribe(seat4)}, wantStatus: StatusInProgress, wantTurn: testX, wantBribes: []int{1, 1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.Teams = true
+			game, _ := applyAll(t, newTestGame(t, rules), rand.New(rand.NewSource(1)), tt.actions...)
+
+			if game.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", game.Status, tt.wantStatus)
+			}
+			if !slices.Equal(game.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", game.Winners, tt.wantWinners)
+			}
+			if tt.wantStatus == StatusInProgress && game.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", game.CurrentTurn, tt.wantTurn)
+			}
+			if !slices.Equal(game.BribeCounts, tt.wantBribes) {
+				t.Errorf("BribeCounts = %v, want %v", game.BribeCounts, tt.wantBribes)
+			}
+			if game.FavoredPlayer != tt.wantFavored {
+				t.Errorf("FavoredPlayer = %d, want %d", game.FavoredPlayer, tt.wantFavored)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"math/rand"
+	"time"
+
+	"xicserver/models"
+)
+
+// StartMatch は持ち時間をルールに従って初期化し、先手のプレイヤーの手番を開始する
+func StartMatch(game *models.Game, firstPlayerID uint, now time.Time) {
+	game.Clocks = nil
+	if game.Rules.TimeBank > 0 {
+		game.Clocks = make(map[uint]time.Duration)
+		for _, player := range game.Players {
+			if player != nil {
+				game.Clocks[player.ID] = time.Duration(game.Rules.TimeBank) * time.Second
+			}
+		}
+	}
+	game.Hands = dealPowerUps(game)
+	game.FirstMover = firstPlayerID
+	StartTurn(game, firstPlayerID, now)
+}
+
+// StartTurn は手番をプレイヤーに渡し、1手の制限時間と持ち時間のうち早い方を手番の期限に設定する。
+// 同時手番では全員の手番となるため、CurrentTurnは0になる
+func StartTurn(game *models.Game, playerID uint, now time.Time) {
+	if game.Rules.Simultaneous {
+		playerID = 0
+	}
+	game.CurrentTurn = playerID
+	game.TurnStartedAt = now
+	game.TurnDeadline = time.Time{}
+	if game.Rules.TurnTimeLimit > 0 {
+		game.TurnDeadline = turnLimitDeadline(game)
+	}
+	if remaining, ok := game.Clocks[playerID]; ok {
+		if clockDeadline := now.Add(remaining); game.TurnDeadline.IsZero() || clockDeadline.Before(game.TurnDeadline) {
+			game.TurnDeadline = clockDeadline
+		}
+	}
+}
+
+// ラウンドの終了時に手番の期限と持ち時間の計測を止めるヘルパー関数
+func stopTurn(game *models.Game) {
+	game.TurnStartedAt = time.Time{}
+	game.TurnDeadline = time.Time{}
+}
+
+// 1手の制限時間による手番の期限を返すヘルパー関数
+func turnLimitDeadline(game *models.Game) time.Time {
+	return game.TurnStartedAt.Add(time.Duration(game.Rules.TurnTimeLimit) * time.Second)
+}
+
+// 手番のプレイヤーの持ち時間から経過時間を差し引き、加算時間を足すヘルパー関数。
+// 持ち時間が尽きていた場合はfalseを返す
+func chargeClock(game *models.Game, playerID uint, now time.Time) bool {
+	if _, ok := game.Clocks[playerID]; !ok || game.TurnStartedAt.IsZero() {
+		return true
+	}
+	if !deductClock(game, playerID, now) {
+		return false
+	}
+	game.Clocks[playerID] += time.Duration(game.Rules.TimeIncrement) * time.Second
+	return true
+}
+
+// 手番のプレイヤーの持ち時間から経過時間だけを差し引くヘルパー関数。時間切れで強制された手には加算時間を足さない。
+// 持ち時間が尽きていた場合はfalseを返す
+func deductClock(game *models.Game, playerID uint, now time.Time) bool {
+	remaining, ok := game.Clocks[playerID]
+	if !ok || game.TurnStartedAt.IsZero() {
+		return true
+	}
+	remaining -= now.Sub(game.TurnStartedAt)
+	if remaining <= 0 {
+		game.Clocks[playerID] = 0
+		return false
+	}
+	game.Clocks[playerID] = remaining
+	game.TurnStartedAt = now // 同じ手番で二重に差し引かないようにする
+	return true
+}
+
+// 持ち時間を使い切ったプレイヤーの負けとしてマッチを終了するヘルパー関数
+func flagPlayer(game *models.Game, playerID uint) []Event {
+	events := []Event{messageAll("SYSTEM: Time bank exhausted! The match is over."), {Type: EventResults}}
+	return finishMatchWithWinner(game, beneficiaryOf(game, playerID), FinishFlagged, events)
+}
+
+// 手番の期限が切れた場合に、持ち時間切れなら負け、1手の制限時間切れならルールで決められたペナルティを適用する
+func applyTimeout(game *models.Game, action Action, randGen *rand.Rand) ([]Event, error) {
+	if game.Status != StatusInProgress || game.TurnDeadline.IsZero() || action.At.Before(game.TurnDeadline) {
+		return nil, ErrTimerNotExpired
+	}
+	if game.Rules.Simultaneous {
+		return applySimultaneousTimeout(game, action, randGen)
+	}
+
+	currentPlayerIndex := playerIndex(game, game.CurrentTurn)
+	if currentPlayerIndex == -1 {
+		return nil, ErrPlayerNotFound
+	}
+
+	// deductClockは手番の開始時刻を更新するため、1手の制限時間の期限は更新前に求めておく
+	limitDeadline := turnLimitDeadline(game)
+	// ランダムに印を置いた手を待ったで取り消せるように、差し引く前の持ち時間を残しておく
+	clocks := copyClocks(game.Clocks)
+	// 持ち時間を使い切っていればマッチを終了。時間切れの手番には加算時間を足さない
+	if !deductClock(game, game.CurrentTurn, action.At) {
+		return flagPlayer(game, game.CurrentTurn), nil
+	}
+	if game.Rules.TurnTimeLimit == 0 || action.At.Before(limitDeadline) {
+		return nil, ErrTimerNotExpired
+	}
+	// 量子三目並べで測定待ちの場合は、ペナルティの代わりに審判がランダムに測定する
+	if game.Quantum != nil && game.Quantum.Collapse != nil {
+		return timeoutCollapse(game, action.At, randGen), nil
+	}
+
+	switch game.Rules.TimeoutPenalty {
+	case models.TimeoutSkipTurn:
+		// 手番を次のプレイヤーに渡す
+		events := []Event{messageAll("SYSTEM: Time is up! The turn has been skipped.")}
+		StartTurn(game, nextPlayerID(game, game.CurrentTurn), action.At)
+		return append(events, Event{Type: EventGameState}), nil
+	case models.TimeoutForfeitRound:
+		// 相手の勝利としてラウンドを終了する
+		events := []Event{messageAll("SYSTEM: Time is up! The round has been forfeited.")}
+		return append(events, finishRound(game, beneficiaryOf(game, game.CurrentTurn), nil)...), nil
+	default:
+		// 空いているセルにランダムに印を置く
+		events := []Event{messageAll("SYSTEM: Time is up! A mark has been placed at random.")}
+		emptyCells := variantFor(game.Rules).candidates(game, game.CurrentTurn)
+		if len(emptyCells) == 0 {
+			// 盤面が埋まった時点でラウンドは終了しているため、通常は到達しない
+			return nil, ErrTimerNotExpired
+		}
+		chosenCell := emptyCells[randGen.Intn(len(emptyCells))]
+		recordMove(game, game.CurrentTurn, clocks)
+		if game.Quantum != nil {
+			// 量子三目並べでは2つ目のセルもランダムに選んで重ね合わせの印を置く
+			second := chosenCell
+			if others := withoutCell(emptyCells, chosenCell[0], chosenCell[1]); len(others) > 0 {
+				second = others[randGen.Intn(len(others))]
+			}
+			return append(events, placeQuantumMark(game, game.CurrentTurn, chosenCell, second, action.At, randGen)...), nil
+		}
+		game.Board[chosenCell[0]][chosenCell[1]] = randomMarkSymbol(game, game.CurrentTurn, randGen)
+		return append(events, finishMove(game, chosenCell, action.At, randGen)...), nil
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"xicserver/models"
+)
+
+func TestApplyTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		turnLimit    int
+		timeBank     int
+		increment    int
+		elapsed      time.Duration
+		wantErr      error
+		wantStatus   string
+		wantTurn     uint
+		wantClock    time.Duration
+		wantFinished string
+	}{
+		{name: "turn limit only", turnLimit: 10, elapsed: 10 * time.Second, wantStatus: StatusInProgress, wantTurn: testO},
+		{name: "turn limit only before the deadline", turnLimit: 10, elapsed: 9 * time.Second, wantErr: ErrTimerNotExpired},
+		{name: "turn limit with a time bank", turnLimit: 10, timeBank: 600, elapsed: 10 * time.Second, wantStatus: StatusInProgress, wantTurn: testO, wantClock: 590 * time.Second},
+		{name: "timeout earns no increment", turnLimit: 10, timeBank: 600, increment: 5, elapsed: 10 * time.Second, wantStatus: StatusInProgress, wantTurn: testO, wantClock: 590 * time.Second},
+		{name: "turn limit with a time bank before the deadline", turnLimit: 10, timeBank: 600, elapsed: 9 * time.Second, wantErr: ErrTimerNotExpired},
+		{name: "time bank runs out before the turn limit", turnLimit: 10, timeBank: 5, elapsed: 5 * time.Second, wantStatus: StatusFinished, wantFinished: FinishFlagged},
+		{name: "time bank only", timeBank: 5, elapsed: 5 * time.Second, wantStatus: StatusFinished, wantFinished: FinishFlagged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.TurnTimeLimit = tt.turnLimit
+			rules.TimeBank = tt.timeBank
+			rules.TimeIncrement = tt.increment
+			rules.TimeoutPenalty = models.TimeoutSkipTurn
+			game := newTestGame(t, rules)
+
+			next, _, err := Apply(game, Action{Type: ActionTimeout, At: testStart.Add(tt.elapsed)}, rand.New(rand.NewSource(1)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if next.Status != tt.wantStatus || next.FinishReason != tt.wantFinished {
+				t.Fatalf("Status = %q, FinishReason = %q, want %q and %q", next.Status, next.FinishReason, tt.wantStatus, tt.wantFinished)
+			}
+			if tt.wantStatus == StatusFinished {
+				if next.MatchWinner != testO {
+					t.Errorf("MatchWinner = %d, want %d", next.MatchWinner, testO)
+				}
+				return
+			}
+			if next.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, tt.wantTurn)
+			}
+			if got := next.Clocks[testX]; got != tt.wantClock {
+				t.Errorf("Clocks[X] = %v, want %v", got, tt.wantClock)
+			}
+		})
+	}
+}
+
+func TestTimeoutPenalty(t *testing.T) {
+	tests := []struct {
+		name        string
+		turnLimit   int
+		penalty     string
+		elapsed     time.Duration
+		wantErr     error
+		wantStatus  string
+		wantTurn    uint
+		wantMarks   int
+		wantWinners []uint
+	}{
+		{name: "skip turn", turnLimit: 10, penalty: models.TimeoutSkipTurn, elapsed: 10 * time.Second, wantStatus: StatusInProgress, wantTurn: testO},
+		{name: "random move", turnLimit: 10, penalty: models.TimeoutRandomMove, elapsed: 10 * time.Second, wantStatus: StatusInProgress, wantTurn: testO, wantMarks: 1},
+		{name: "forfeit round", turnLimit: 10, penalty: models.TimeoutForfeitRound, elapsed: 10 * time.Second, wantStatus: StatusRoundFinished, wantWinners: []uint{testO}},
+		{name: "before the deadline", turnLimit: 10, penalty: models.TimeoutSkipTurn, elapsed: 9 * time.Second, wantErr: ErrTimerNotExpired},
+		{name: "no turn limit", penalty: models.TimeoutSkipTurn, elapsed: time.Hour, wantErr: ErrTimerNotExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.TurnTimeLimit = tt.turnLimit
+			rules.TimeoutPenalty = tt.penalty
+			game := newTestGame(t, rules)
+
+			next, _, err := Apply(game, Action{Type: ActionTimeout, At: testStart.Add(tt.elapsed)}, rand.New(rand.NewSource(1)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if next.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == StatusInProgress && next.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", next.CurrentTurn, tt.wantTurn)
+			}
+			if got := countMarks(next.Board); got != tt.wantMarks {
+				t.Errorf("marks on board = %d, want %d", got, tt.wantMarks)
+			}
+			if !reflect.DeepEqual(next.Winners, tt.wantWinners) {
+				t.Errorf("Winners = %v, want %v", next.Winners, tt.wantWinners)
+			}
+		})
+	}
+}
+
+func TestChargeClockOnMove(t *testing.T) {
+	tests := []struct {
+		name        string
+		penalty     string
+		action      Action
+		wantStatus  string
+		wantClock   time.Duration
+		wantHistory int
+	}{
+		{
+			name:        "move earns the increment",
+			action:      Action{Type: ActionMarkCell, PlayerID: testX, X: 0, Y: 0, At: testStart.Add(3 * time.Second)},
+			wantStatus:  StatusInProgress,
+			wantClock:   62 * time.Second,
+			wantHistory: 1,
+		},
+		{
+			// 時間切れで置かれたランダムな手は待ったで取り消せるよう記録するが、加算時間は足さない
+			name:        "random move on timeout earns no increment",
+			penalty:     models.TimeoutRandomMove,
+			action:      Action{Type: ActionTimeout, At: testStart.Add(10 * time.Second)},
+			wantStatus:  StatusInProgress,
+			wantClock:   50 * time.Second,
+			wantHistory: 1,
+		},
+		{
+			// 持ち時間が尽きた後の手は記録せずにマッチを終了する
+			name:       "flagged move leaves no history",
+			action:     Action{Type: ActionMarkCell, PlayerID: testX, X: 0, Y: 0, At: testStart.Add(60 * time.Second)},
+			wantStatus: StatusFinished,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.TurnTimeLimit = 10
+			rules.TimeBank = 60
+			rules.TimeIncrement = 5
+			rules.TimeoutPenalty = tt.penalty
+			game := newTestGame(t, rules)
+
+			next, _, err := Apply(game, tt.action, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatalf("Apply() returned error: %v", err)
+			}
+			if next.Status != tt.wantStatus {
+				t.Fatalf("Status = %q, want %q", next.Status, tt.wantStatus)
+			}
+			if got := next.Clocks[testX]; got != tt.wantClock {
+				t.Errorf("Clocks[X] = %v, want %v", got, tt.wantClock)
+			}
+			if len(next.History) != tt.wantHistory {
+				t.Fatalf("len(History) = %d, want %d", len(next.History), tt.wantHistory)
+			}
+			// 待ったで戻せるように、履歴には差し引く前の持ち時間を残す
+			if tt.wantHistory > 0 && next.History[0].Clocks[testX] != 60*time.Second {
+				t.Errorf("History[0].Clocks[X] = %v, want %v", next.History[0].Clocks[testX], 60*time.Second)
+			}
+		})
+	}
+}
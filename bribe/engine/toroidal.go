@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// toroidalVariant は盤面の端がつながっているルール。右端の列は左端に、下端の行は上端に続く。
+// 印の置き方は標準ルールと同じで、勝敗判定だけが端をまたぐ列を数える
+type toroidalVariant struct {
+	standardVariant
+}
+
+func (toroidalVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	for _, d := range lineDirections {
+		cells := wrappedLineAt(game.Board, cell[0], cell[1], d.dx, d.dy, symbol)
+		if len(cells) == game.Rules.WinLength || (!game.Rules.ExactLength && len(cells) > game.Rules.WinLength) {
+			return playerID, &models.WinLine{Cells: cells, Direction: d.name}, true
+		}
+	}
+	// ボードが全て埋まっているが、勝者がいない場合（引き分け）
+	return 0, nil, isBoardFull(game.Board)
+}
+
+// 端がつながった盤面で、(x, y)を含み(dx, dy)の方向に同じ印が途切れずに続く列のセルを端から順に返すヘルパー関数。
+// 一周して同じセルに戻る場合は、一周分のセルで打ち切る
+func wrappedLineAt(board [][]string, x, y, dx, dy int, symbol string) [][2]int {
+	height, width := len(board), len(board[0])
+	cycle := wrapCycle(height, width, dx, dy)
+	at := func(i int) [2]int {
+		return [2]int{wrapIndex(x+i*dx, height), wrapIndex(y+i*dy, width)}
+	}
+	matches := func(i int) bool {
+		c := at(i)
+		return board[c[0]][c[1]] == symbol
+	}
+
+	start := 0
+	for start > -(cycle-1) && matches(start-1) {
+		start--
+	}
+	var cells [][2]int
+	for i := start; i < start+cycle && matches(i); i++ {
+		cells = append(cells, at(i))
+	}
+	return cells
+}
+
+// (dx, dy)の方向に進んで元のセルに戻るまでのセルの数を返すヘルパー関数
+func wrapCycle(height, width, dx, dy int) int {
+	switch {
+	case dx == 0:
+		return width
+	case dy == 0:
+		return height
+	default:
+		return height / gcd(height, width) * width
+	}
+}
+
+// 最大公約数を返すヘルパー関数
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// 座標を盤面の範囲に折り返すヘルパー関数
+func wrapIndex(i, size int) int {
+	return ((i % size) + size) % size
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestToroidalJudge(t *testing.T) {
+	tests := []struct {
+		name          string
+		board         []string
+		cell          [2]int
+		wantWin       bool
+		wantDirection string
+		wantCells     [][2]int
+	}{
+		{
+			name:          "row across the right edge",
+			board:         []string{"XX.X", "....", "....", "...."},
+			cell:          [2]int{0, 1},
+			wantWin:       true,
+			wantDirection: DirectionRow,
+			wantCells:     [][2]int{{0, 3}, {0, 0}, {0, 1}},
+		},
+		{
+			name:          "column across the bottom edge",
+			board:         []string{"..X.", "..X.", "....", "..X."},
+			cell:          [2]int{3, 2},
+			wantWin:       true,
+			wantDirection: DirectionColumn,
+			wantCells:     [][2]int{{3, 2}, {0, 2}, {1, 2}},
+		},
+		{
+			name:          "diagonal across the corner",
+			board:         []string{"X...", ".X..", "....", "...X"},
+			cell:          [2]int{0, 0},
+			wantWin:       true,
+			wantDirection: DirectionDiagonal,
+			wantCells:     [][2]int{{3, 3}, {0, 0}, {1, 1}},
+		},
+		{
+			name:          "anti-diagonal across two edges",
+			board:         []string{"X...", "...X", "....", ".X.."},
+			cell:          [2]int{0, 0},
+			wantWin:       true,
+			wantDirection: DirectionAntiDiagonal,
+			wantCells:     [][2]int{{3, 1}, {0, 0}, {1, 3}},
+		},
+		{
+			// 一周した列は同じセルを数え直さない
+			name:          "full ring",
+			board:         []string{"XXXX", "....", "....", "...."},
+			cell:          [2]int{0, 2},
+			wantWin:       true,
+			wantDirection: DirectionRow,
+			wantCells:     [][2]int{{0, 3}, {0, 0}, {0, 1}, {0, 2}},
+		},
+		{
+			name:  "gap across the edge",
+			board: []string{"X.X.", "....", "....", "...."},
+			cell:  [2]int{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.GameRules{Variant: models.VariantToroidal, BoardWidth: 4, BoardHeight: 4, WinLength: 3}
+			game := &models.Game{Rules: rules, Board: parseBoard(tt.board...)}
+
+			winner, winLine, finished := toroidalVariant{}.judge(game, tt.cell, testX, "X")
+			if !tt.wantWin {
+				if winner != 0 || winLine != nil || finished {
+					t.Fatalf("judge = (%d, %+v, %v), want no win", winner, winLine, finished)
+				}
+				return
+			}
+			if winner != testX || winLine == nil || !finished {
+				t.Fatalf("judge = (%d, %+v, %v), want a win for %d", winner, winLine, finished, testX)
+			}
+			if winLine.Direction != tt.wantDirection {
+				t.Errorf("Direction = %q, want %q", winLine.Direction, tt.wantDirection)
+			}
+			if !reflect.DeepEqual(winLine.Cells, tt.wantCells) {
+				t.Errorf("Cells = %v, want %v", winLine.Cells, tt.wantCells)
+			}
+		})
+	}
+}
+
+func TestToroidalLineWindows(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          int
+		wrapped       [][2]int // 端をまたいで含まれるべき列
+	}{
+		{
+			name:  "square board",
+			width: 4, height: 4,
+			// 全てのセルから4方向に1つずつ
+			want:    64,
+			wrapped: [][2]int{{3, 3}, {0, 0}, {1, 1}},
+		},
+		{
+			// 幅2の行はWinLengthより短い周期で一周するため数えない
+			name:  "narrow board",
+			width: 2, height: 4,
+			want:    24,
+			wrapped: [][2]int{{2, 1}, {3, 0}, {0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.GameRules{Variant: models.VariantToroidal, BoardWidth: tt.width, BoardHeight: tt.height, WinLength: 3}
+			windows := LineWindows(rules)
+			if len(windows) != tt.want {
+				t.Errorf("len(LineWindows) = %d, want %d", len(windows), tt.want)
+			}
+			if !slices.ContainsFunc(windows, func(window [][2]int) bool { return reflect.DeepEqual(window, tt.wrapped) }) {
+				t.Errorf("LineWindows does not contain the wrapped line %v", tt.wrapped)
+			}
+		})
+	}
+}
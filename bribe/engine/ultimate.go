@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"xicserver/models"
+)
+
+// アルティメット三目並べの小盤面の大きさと、引き分けになった小盤面の印
+const (
+	ultimateSubSize  = 3
+	ultimateDrawMark = "-"
+)
+
+// ultimateVariant は3x3の小盤面を3x3に並べたアルティメット三目並べ。
+// 置いたセルの小盤面内の位置が、相手が次に置く小盤面を決める。小盤面で三目を並べるとその小盤面を獲得し、
+// 獲得した小盤面を三つ並べるとラウンドの勝利となる
+type ultimateVariant struct{}
+
+func (ultimateVariant) reset(game *models.Game) {
+	game.Board = NewBoard(game.Rules)
+	game.Ultimate = &models.UltimateState{
+		SubBoards: NewBoard(models.GameRules{BoardWidth: ultimateSubSize, BoardHeight: ultimateSubSize}),
+	}
+}
+
+func (ultimateVariant) locate(game *models.Game, action Action) ([2]int, error) {
+	return locatePlanar(game, action)
+}
+
+func (ultimateVariant) checkMove(game *models.Game, playerID uint, x, y int) error {
+	if !isPlayableSubBoard(game.Ultimate, subBoardOf(x, y)) {
+		return ErrWrongSubBoard
+	}
+	return nil
+}
+
+func (ultimateVariant) candidates(game *models.Game, playerID uint) [][2]int {
+	var cells [][2]int
+	for _, cell := range getEmptyCellsExcept(game.Board, -1, -1) {
+		if isPlayableSubBoard(game.Ultimate, subBoardOf(cell[0], cell[1])) {
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+func (v ultimateVariant) alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int {
+	return anyOtherCell(v, game, playerID, selected)
+}
+
+func (ultimateVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	state := game.Ultimate
+	sub := subBoardOf(cell[0], cell[1])
+
+	// 置いたセルの小盤面の決着を判定
+	local := subBoard(game.Board, sub)
+	if localLine := checkWinAt(local, cell[0]%ultimateSubSize, cell[1]%ultimateSubSize, symbol, ultimateSubSize, false); localLine != nil {
+		state.SubBoards[sub[0]][sub[1]] = symbol
+		// 獲得した小盤面が三つ並べば勝利
+		if winLine := checkWinAt(state.SubBoards, sub[0], sub[1], symbol, ultimateSubSize, false); winLine != nil {
+			return playerID, ultimateWinLine(game.Board, winLine, sub, localLine, symbol), true
+		}
+	} else if isBoardFull(local) {
+		state.SubBoards[sub[0]][sub[1]] = ultimateDrawMark
+	}
+
+	// 全ての小盤面が決着して勝者がいない場合は引き分け
+	if isBoardFull(state.SubBoards) {
+		return 0, nil, true
+	}
+
+	// 相手は置いたセルの位置に対応する小盤面に置く。その小盤面が決着済みなら任意の小盤面に置ける
+	target := [2]int{cell[0] % ultimateSubSize, cell[1] % ultimateSubSize}
+	state.Target = nil
+	if state.SubBoards[target[0]][target[1]] == "" {
+		state.Target = &target
+	}
+	return 0, nil, false
+}
+
+// 小盤面の座標で求めた列を、盤面のセルの座標の列に変換するヘルパー関数。
+// Cellsには各小盤面で揃った列のセルを、SubBoardsには列を構成する小盤面の座標を格納する。
+// 最後に獲得した小盤面では、置いた印を含む列を使う。アルティメットではパワーアップを使えず、
+// 獲得した小盤面の印は変わらないため、どの小盤面にも揃った列が残っている
+func ultimateWinLine(board [][]string, subLine *models.WinLine, lastSub [2]int, lastLine *models.WinLine, symbol string) *models.WinLine {
+	winLine := &models.WinLine{Direction: subLine.Direction, SubBoards: subLine.Cells}
+	for _, sub := range subLine.Cells {
+		localLine := lastLine
+		if sub != lastSub {
+			localLine = checkWin(subBoard(board, sub), symbol, ultimateSubSize)
+		}
+		for _, local := range localLine.Cells {
+			winLine.Cells = append(winLine.Cells, [2]int{sub[0]*ultimateSubSize + local[0], sub[1]*ultimateSubSize + local[1]})
+		}
+	}
+	return winLine
+}
+
+// セルが属する小盤面の座標を返すヘルパー関数
+func subBoardOf(x, y int) [2]int {
+	return [2]int{x / ultimateSubSize, y / ultimateSubSize}
+}
+
+// 小盤面に印を置けるかを返すヘルパー関数
+func isPlayableSubBoard(state *models.UltimateState, sub [2]int) bool {
+	if state.SubBoards[sub[0]][sub[1]] != "" {
+		return false
+	}
+	return state.Target == nil || *state.Target == sub
+}
+
+// 小盤面のセルを切り出した盤面を返すヘルパー関数
+func subBoard(board [][]string, sub [2]int) [][]string {
+	local := make([][]string, ultimateSubSize)
+	for i := range local {
+		row := board[sub[0]*ultimateSubSize+i]
+		local[i] = append([]string(nil), row[sub[1]*ultimateSubSize:(sub[1]+1)*ultimateSubSize]...)
+	}
+	return local
+}
+
+// アルティメット三目並べの状態の複製を返すヘルパー関数
+func copyUltimate(state *models.UltimateState) *models.UltimateState {
+	if state == nil {
+		return nil
+	}
+	copied := &models.UltimateState{SubBoards: copyBoard(state.SubBoards)}
+	if state.Target != nil {
+		target := *state.Target
+		copied.Target = &target
+	}
+	return copied
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"xicserver/models"
+)
+
+func TestUltimateJudgeWinLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		board         []string
+		cell          [2]int
+		wantSubBoards [][2]int
+		wantCells     [][2]int
+	}{
+		{
+			name: "row of sub-boards",
+			board: []string{
+				"XXXXXXXX.",
+				".........",
+				".........",
+				".........",
+				".........",
+				".........",
+				".........",
+				".........",
+				".........",
+			},
+			cell:          [2]int{0, 8},
+			wantSubBoards: [][2]int{{0, 0}, {0, 1}, {0, 2}},
+			wantCells:     lineCells(0, 0, 0, 1, 9),
+		},
+		{
+			name: "diagonal of sub-boards with columns inside",
+			board: []string{
+				"X........",
+				"X........",
+				"X........",
+				"....X....",
+				"....X....",
+				"....X....",
+				"........X",
+				"........X",
+				".........",
+			},
+			cell:          [2]int{8, 8},
+			wantSubBoards: [][2]int{{0, 0}, {1, 1}, {2, 2}},
+			wantCells:     [][2]int{{0, 0}, {1, 0}, {2, 0}, {3, 4}, {4, 4}, {5, 4}, {6, 8}, {7, 8}, {8, 8}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.GameRules{Variant: models.VariantUltimate, BoardWidth: 9, BoardHeight: 9, WinLength: 3}
+			game := &models.Game{Rules: rules}
+			ultimateVariant{}.reset(game)
+			game.Board = parseBoard(tt.board...)
+			game.Board[tt.cell[0]][tt.cell[1]] = "X"
+			for _, sub := range tt.wantSubBoards[:2] {
+				game.Ultimate.SubBoards[sub[0]][sub[1]] = "X"
+			}
+
+			winner, winLine, finished := ultimateVariant{}.judge(game, tt.cell, testX, "X")
+			if winner != testX || !finished || winLine == nil {
+				t.Fatalf("judge() = %d, %+v, %v, want a win for %d", winner, winLine, finished, testX)
+			}
+			if !reflect.DeepEqual(winLine.SubBoards, tt.wantSubBoards) {
+				t.Errorf("SubBoards = %v, want %v", winLine.SubBoards, tt.wantSubBoards)
+			}
+			cells := slices.Clone(winLine.Cells)
+			slices.SortFunc(cells, func(a, b [2]int) int {
+				if a[0] != b[0] {
+					return a[0] - b[0]
+				}
+				return a[1] - b[1]
+			})
+			if !reflect.DeepEqual(cells, tt.wantCells) {
+				t.Errorf("Cells = %v, want %v", cells, tt.wantCells)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"slices"
+	"time"
+
+	"xicserver/models"
+)
+
+// 印を置く直前の状態を履歴に記録するヘルパー関数。保留中の待ったの要求は新しい手で取り消される。
+// clocksには、待ったで手に使った時間も戻せるように、持ち時間から経過時間を差し引く前の持ち時間を渡す
+func recordMove(game *models.Game, playerID uint, clocks map[uint]time.Duration) {
+	game.History = append(game.History, models.MoveSnapshot{
+		PlayerID:      playerID,
+		Board:         copyBoard(game.Board),
+		CurrentTurn:   game.CurrentTurn,
+		RefereeCount:  game.RefereeCount,
+		BiasDegree:    game.BiasDegree,
+		FavoredPlayer: game.FavoredPlayer,
+		RefereeStatus: game.RefereeStatus,
+		LastMove:      game.LastMove,
+		Ultimate:      copyUltimate(game.Ultimate),
+		Hands:         copyHands(game.Hands),
+		Shielded:      append([][2]int(nil), game.Shielded...),
+		Revealed:      copyRevealed(game.Revealed),
+		Clocks:        clocks,
+	})
+	game.UndoRequest = nil
+}
+
+// 直前の自分の手を取り消すよう対戦相手に求める
+func applyUndoRequest(game *models.Game, action Action) ([]Event, error) {
+	if !game.Rules.AllowUndo {
+		return nil, ErrUndoDisabled
+	}
+	if playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrPlayerNotFound
+	}
+	if game.Status != StatusInProgress {
+		return nil, ErrRoundNotInProgress
+	}
+	if len(game.History) == 0 || game.History[len(game.History)-1].PlayerID != action.PlayerID {
+		return nil, ErrNothingToUndo
+	}
+	if game.UndoRequest != nil {
+		return nil, ErrUndoRequestPending
+	}
+	// ラウンドを投了したプレイヤーに手番が戻らないようにする
+	if slices.Contains(game.RoundResigned, action.PlayerID) {
+		return nil, ErrAlreadyResigned
+	}
+
+	game.UndoRequest = &models.UndoRequest{From: action.PlayerID}
+	return []Event{
+		messageTo(opponentID(game, action.PlayerID), "SYSTEM: Your opponent asks to take back the last move!"),
+		{Type: EventGameState},
+	}, nil
+}
+
+// 待ったの要求を受諾した場合は、盤面と審判の状態、公開されたセルと持ち時間を直前の手の前に戻す
+func applyUndoResponse(game *models.Game, action Action) ([]Event, error) {
+	request := game.UndoRequest
+	if request == nil || request.From == action.PlayerID || playerIndex(game, action.PlayerID) == -1 {
+		return nil, ErrNoUndoRequest
+	}
+	game.UndoRequest = nil
+
+	if !action.Accept {
+		return []Event{
+			messageTo(request.From, "SYSTEM: Your takeback request was declined."),
+			{Type: EventGameState},
+		}, nil
+	}
+
+	last := game.History[len(game.History)-1]
+	game.History = game.History[:len(game.History)-1]
+	game.Board = copyBoard(last.Board)
+	game.RefereeCount = last.RefereeCount
+	game.BiasDegree = last.BiasDegree
+	game.FavoredPlayer = last.FavoredPlayer
+	game.RefereeStatus = last.RefereeStatus
+	game.LastMove = last.LastMove
+	game.Ultimate = copyUltimate(last.Ultimate)
+	game.Hands = copyHands(last.Hands)
+	game.Shielded = last.Shielded
+	game.Revealed = copyRevealed(last.Revealed)
+	game.Clocks = copyClocks(last.Clocks)
+	StartTurn(game, last.CurrentTurn, action.At)
+
+	return []Event{
+		messageAll("SYSTEM: The last move was taken back."),
+		{Type: EventGameState},
+	}, nil
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestApplyUndoResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       bool
+		wantMark     string
+		wantRevealed bool
+		wantTurn     uint
+		wantClock    time.Duration
+	}{
+		{name: "accepted", accept: true, wantTurn: testX, wantClock: 600 * time.Second},
+		{name: "declined", accept: false, wantMark: "X", wantRevealed: true, wantTurn: testO, wantClock: 595 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules()
+			rules.Fog = true
+			rules.AllowUndo = true
+			rules.TimeBank = 600
+			randGen := rand.New(rand.NewSource(1))
+			game, _ := applyAll(t, newTestGame(t, rules), randGen,
+				Action{Type: ActionMarkCell, PlayerID: testX, X: 1, Y: 1, At: testStart.Add(5 * time.Second)},
+				// Oが隠れたXの印を選んで失敗し、セルが公開される
+				Action{Type: ActionMarkCell, PlayerID: testO, X: 1, Y: 1, At: testStart.Add(6 * time.Second)},
+				Action{Type: ActionUndoRequest, PlayerID: testX},
+				Action{Type: ActionUndoResponse, PlayerID: testO, Accept: tt.accept, At: testStart.Add(7 * time.Second)},
+			)
+
+			if got := game.Board[1][1]; got != tt.wantMark {
+				t.Errorf("Board[1][1] = %q, want %q", got, tt.wantMark)
+			}
+			if got := game.Revealed != nil && game.Revealed[1][1]; got != tt.wantRevealed {
+				t.Errorf("Revealed[1][1] = %v, want %v", got, tt.wantRevealed)
+			}
+			if game.CurrentTurn != tt.wantTurn {
+				t.Errorf("CurrentTurn = %d, want %d", game.CurrentTurn, tt.wantTurn)
+			}
+			if got := game.Clocks[testX]; got != tt.wantClock {
+				t.Errorf("Clocks[X] = %v, want %v", got, tt.wantClock)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"math/rand"
+
+	"xicserver/models"
+)
+
+// variant はルールのバリエーションごとに異なる、盤面の初期化・印の配置・勝敗判定の規則
+type variant interface {
+	// ラウンド開始時の盤面と、バリエーション固有の状態を用意する
+	reset(game *models.Game)
+	// アクションで選択されたセルを盤面の座標に変換する。盤面の範囲外の場合はErrInvalidCell
+	locate(game *models.Game, action Action) ([2]int, error)
+	// 選択されたセルに印を置けるかを検証する。盤面の範囲と空きは検証済み
+	checkMove(game *models.Game, playerID uint, x, y int) error
+	// プレイヤーが印を置ける全てのセル。タイムアウト時にランダムに置く先の候補
+	candidates(game *models.Game, playerID uint) [][2]int
+	// 審判が選択されたセルの代わりに印を置く先の候補。空の場合は選択どおりに置く
+	alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int
+	// cellに印が置かれた後の勝敗判定。ラウンドが終わる場合はdoneがtrueで、winnerIDは勝者（引き分けは0）
+	judge(game *models.Game, cell [2]int, playerID uint, symbol string) (winnerID uint, winLine *models.WinLine, done bool)
+}
+
+// ルール設定に対応するバリエーションを返すヘルパー関数
+func variantFor(rules models.GameRules) variant {
+	switch rules.Variant {
+	case models.VariantUltimate:
+		return ultimateVariant{}
+	case models.VariantCube:
+		return cubeVariant{}
+	case models.VariantGravity:
+		return gravityVariant{}
+	case models.VariantOrderChaos:
+		return orderChaosVariant{}
+	case models.VariantQuantum:
+		return quantumVariant{}
+	case models.VariantToroidal:
+		return toroidalVariant{}
+	default:
+		return standardVariant{}
+	}
+}
+
+// ResetBoard はルール設定に従ってゲームの盤面を空にし、バリエーション固有の状態を初期化する。
+// 障害物のあるルールでは、盤面にランダムに障害物を配置する。勝利可能な配置が見つからない場合は、
+// 障害物のない盤面でErrNoObstacleLayoutを返す
+func ResetBoard(game *models.Game, randGen *rand.Rand) error {
+	variantFor(game.Rules).reset(game)
+	err := placeObstacles(game, randGen)
+	game.Revealed = nil
+	game.Shielded = nil
+	game.RoundResigned = nil
+	return err
+}
+
+// 縦横の盤面でアクションの座標をそのまま盤面の座標として扱うヘルパー関数
+func locatePlanar(game *models.Game, action Action) ([2]int, error) {
+	if !inBounds(game.Board, action.X, action.Y) {
+		return [2]int{}, ErrInvalidCell
+	}
+	return [2]int{action.X, action.Y}, nil
+}
+
+// 審判が選択されたセル以外の置けるセルから選ぶ場合の候補を返すヘルパー関数
+func anyOtherCell(v variant, game *models.Game, playerID uint, selected [2]int) [][2]int {
+	return withoutCell(v.candidates(game, playerID), selected[0], selected[1])
+}
+
+// セルのリストから指定されたセルを除いたリストを返すヘルパー関数
+func withoutCell(cells [][2]int, x, y int) [][2]int {
+	filtered := make([][2]int, 0, len(cells))
+	for _, cell := range cells {
+		if cell != [2]int{x, y} {
+			filtered = append(filtered, cell)
+		}
+	}
+	return filtered
+}
+
+// standardVariant は縦横の盤面でWinLength個を並べる標準ルール。ExactLengthと連珠の禁じ手にも対応する
+type standardVariant struct{}
+
+func (standardVariant) reset(game *models.Game) {
+	game.Board = NewBoard(game.Rules)
+}
+
+func (standardVariant) locate(game *models.Game, action Action) ([2]int, error) {
+	return locatePlanar(game, action)
+}
+
+func (standardVariant) checkMove(game *models.Game, playerID uint, x, y int) error {
+	// 連珠の先手は禁じ手を選択できない
+	if isRenjuRestricted(game, playerID) && isForbiddenMove(game.Board, x, y, playerSymbol(game, playerID), game.Rules.WinLength) {
+		return ErrForbiddenMove
+	}
+	return nil
+}
+
+func (standardVariant) candidates(game *models.Game, playerID uint) [][2]int {
+	// 連珠の先手の場合、禁じ手には置かない
+	return legalCells(game, playerID, playerSymbol(game, playerID), getEmptyCellsExcept(game.Board, -1, -1))
+}
+
+func (v standardVariant) alternatives(game *models.Game, playerID uint, selected [2]int) [][2]int {
+	return anyOtherCell(v, game, playerID, selected)
+}
+
+func (standardVariant) judge(game *models.Game, cell [2]int, playerID uint, symbol string) (uint, *models.WinLine, bool) {
+	// 最後に置かれた印を含む列だけを調べれば十分なので、盤面全体は走査しない
+	if winLine := winningLineAt(game, cell, playerID, symbol); winLine != nil {
+		return playerID, winLine, true
+	}
+	// ボードが全て埋まっているが、勝者がいない場合（引き分け）
+	return 0, nil, isBoardFull(game.Board)
+}
@@ -0,0 +1,211 @@
+package themes
+
+import (
+	"errors"
+	"fmt"
+
+	"xicserver/models"
+)
+
+// カスタムルールで指定できる値の範囲
+const (
+	MinBoardSize  = 3
+	MaxBoardSize  = 15
+	MinWinLength  = 3
+	MaxRounds     = 9
+	MaxBribeLimit = 20
+	MaxTurnTime   = 300  // 1手の制限時間の上限（秒）
+	MaxTimeBank   = 3600 // 持ち時間の上限（秒）
+	MaxIncrement  = 60   // 1手ごとの加算時間の上限（秒）
+	RenjuLength   = 5    // 連珠の禁じ手を適用できる勝利条件の連続数
+	MinBoardDepth = 2    // 3次元の盤面の層の数の下限
+	MaxCubeSize   = 6    // 3次元の盤面の縦・横・層の数の上限
+	MaxPowerUps   = 3    // 各パワーアップの枚数の上限
+	MaxPlayers    = 3    // チーム戦以外で対戦できる人数の上限
+	TeamPlayers   = 4    // チーム戦の人数
+)
+
+// ErrUnknownTheme は登録されていないRoomThemeが指定された場合のエラー
+var ErrUnknownTheme = errors.New("unknown room theme")
+
+// ValidateRules はルール設定が対戦可能な範囲に収まっているかを検証します。
+func ValidateRules(rules models.GameRules) error {
+	if rules.BoardWidth < MinBoardSize || rules.BoardWidth > MaxBoardSize {
+		return fmt.Errorf("boardWidth must be between %d and %d", MinBoardSize, MaxBoardSize)
+	}
+	if rules.BoardHeight < MinBoardSize || rules.BoardHeight > MaxBoardSize {
+		return fmt.Errorf("boardHeight must be between %d and %d", MinBoardSize, MaxBoardSize)
+	}
+	// 少なくとも縦か横の一列に収まる長さでなければ勝利できない
+	maxWinLength := max(rules.BoardWidth, rules.BoardHeight)
+	if rules.WinLength < MinWinLength || rules.WinLength > maxWinLength {
+		return fmt.Errorf("winLength must be between %d and %d", MinWinLength, maxWinLength)
+	}
+	if rules.Rounds < 1 || rules.Rounds > MaxRounds {
+		return fmt.Errorf("rounds must be between 1 and %d", MaxRounds)
+	}
+	if rules.MatchFormat != models.MatchBestOf && rules.MatchFormat != models.MatchFirstTo {
+		return fmt.Errorf("matchFormat must be %q or %q", models.MatchBestOf, models.MatchFirstTo)
+	}
+	// どちらの形式でも、ラウンド数の中で届かない勝利数は指定できない
+	if rules.TargetWins < 1 || rules.TargetWins > rules.Rounds {
+		return fmt.Errorf("targetWins must be between 1 and rounds (%d)", rules.Rounds)
+	}
+	if rules.Bias != models.BiasFair && rules.Bias != models.BiasBiased {
+		return fmt.Errorf("bias must be %q or %q", models.BiasFair, models.BiasBiased)
+	}
+	if rules.MarkAccuracy < 0 || rules.MarkAccuracy > 1 {
+		return fmt.Errorf("markAccuracy must be between 0 and 1")
+	}
+	if rules.BribeLimit < 0 || rules.BribeLimit > MaxBribeLimit {
+		return fmt.Errorf("bribeLimit must be between 0 and %d", MaxBribeLimit)
+	}
+	if rules.TurnTimeLimit < 0 || rules.TurnTimeLimit > MaxTurnTime {
+		return fmt.Errorf("turnTimeLimit must be between 0 and %d seconds", MaxTurnTime)
+	}
+	if rules.TurnTimeLimit > 0 {
+		switch rules.TimeoutPenalty {
+		case models.TimeoutRandomMove, models.TimeoutSkipTurn, models.TimeoutForfeitRound:
+		default:
+			return fmt.Errorf("timeoutPenalty must be %q, %q or %q", models.TimeoutRandomMove, models.TimeoutSkipTurn, models.TimeoutForfeitRound)
+		}
+	}
+	if rules.TimeBank < 0 || rules.TimeBank > MaxTimeBank {
+		return fmt.Errorf("timeBank must be between 0 and %d seconds", MaxTimeBank)
+	}
+	if rules.TimeIncrement < 0 || rules.TimeIncrement > MaxIncrement {
+		return fmt.Errorf("timeIncrement must be between 0 and %d seconds", MaxIncrement)
+	}
+	if rules.Renju && rules.WinLength != RenjuLength {
+		return fmt.Errorf("renju requires winLength %d", RenjuLength)
+	}
+	// 共有の印や小盤面の状態から隠した印の位置がわかってしまうバリエーションとは組み合わせられない
+	if rules.Fog && (rules.Variant == models.VariantOrderChaos || rules.Variant == models.VariantUltimate) {
+		return fmt.Errorf("fog cannot be combined with orderChaos or ultimate")
+	}
+	// 障害物は盤面のセルの3分の1まで。重力ルールでは障害物の下に印を落とせず、
+	// アルティメットでは小盤面の三目が作れなくなるため組み合わせられない
+	if rules.Obstacles != 0 {
+		cells := rules.BoardWidth * rules.BoardHeight * max(rules.BoardDepth, 1)
+		if rules.Obstacles < 0 || rules.Obstacles > cells/3 {
+			return fmt.Errorf("obstacles must be between 0 and %d", cells/3)
+		}
+		if rules.Variant == models.VariantGravity || rules.Variant == models.VariantUltimate {
+			return fmt.Errorf("obstacles cannot be combined with gravity or ultimate")
+		}
+	}
+	// パワーアップは縦横の盤面で、各プレイヤーが自分の印を持つルールでのみ使える
+	if rules.PowerUps != 0 {
+		if rules.PowerUps < 0 || rules.PowerUps > MaxPowerUps {
+			return fmt.Errorf("powerUps must be between 0 and %d", MaxPowerUps)
+		}
+		if rules.Variant != models.VariantStandard && rules.Variant != models.VariantToroidal {
+			return fmt.Errorf("powerUps are only available for the standard and toroidal variants")
+		}
+		if rules.Fog {
+			return fmt.Errorf("powerUps cannot be combined with fog")
+		}
+	}
+	if rules.Renju && rules.Misere {
+		return fmt.Errorf("renju cannot be combined with misere")
+	}
+	// 3人以上の対戦では、対戦相手が1人であることを前提とするルールとは組み合わせられない。
+	// チーム戦は人数が4人に固定される
+	if rules.Teams && rules.Players != 0 && rules.Players != TeamPlayers {
+		return fmt.Errorf("teams require %d players", TeamPlayers)
+	}
+	if rules.Players != 0 || rules.Teams {
+		if !rules.Teams && (rules.Players < 2 || rules.Players > MaxPlayers) {
+			return fmt.Errorf("players must be between 2 and %d", MaxPlayers)
+		}
+		if (rules.Players > 2 || rules.Teams) && (rules.Variant == models.VariantOrderChaos || rules.PowerUps != 0 || rules.Misere || rules.Renju || rules.AllowUndo) {
+			return fmt.Errorf("more than 2 players cannot be combined with orderChaos, powerUps, misere, renju or allowUndo")
+		}
+	}
+	// 同時手番は2人対戦の縦横の盤面でのみ使え、手番の順序や個人の持ち時間に依存するルールとは組み合わせられない
+	if rules.Simultaneous {
+		if rules.Players > 2 || rules.Teams {
+			return fmt.Errorf("simultaneous moves are only available for 2 players")
+		}
+		if rules.Variant != models.VariantStandard && rules.Variant != models.VariantToroidal {
+			return fmt.Errorf("simultaneous moves are only available for the standard and toroidal variants")
+		}
+		if rules.PowerUps != 0 || rules.Fog || rules.Renju || rules.AllowUndo || rules.TimeBank != 0 {
+			return fmt.Errorf("simultaneous moves cannot be combined with powerUps, fog, renju, allowUndo or timeBank")
+		}
+	}
+	switch rules.Variant {
+	case models.VariantStandard:
+	case models.VariantCube:
+		if rules.BoardDepth < MinBoardDepth || rules.BoardDepth > MaxCubeSize {
+			return fmt.Errorf("boardDepth must be between %d and %d", MinBoardDepth, MaxCubeSize)
+		}
+		if rules.BoardWidth > MaxCubeSize || rules.BoardHeight > MaxCubeSize {
+			return fmt.Errorf("cube boards must be at most %dx%d", MaxCubeSize, MaxCubeSize)
+		}
+		if rules.Renju {
+			return fmt.Errorf("cube cannot be combined with renju")
+		}
+	case models.VariantGravity:
+		if rules.Renju {
+			return fmt.Errorf("gravity cannot be combined with renju")
+		}
+	case models.VariantToroidal:
+		if rules.Renju {
+			return fmt.Errorf("toroidal cannot be combined with renju")
+		}
+	case models.VariantOrderChaos:
+		// どちらの印で揃えてもOrderの勝ちとなるため、先手の禁じ手やミゼールとは組み合わせられない
+		if rules.Renju || rules.Misere {
+			return fmt.Errorf("orderChaos cannot be combined with renju or misere")
+		}
+	case models.VariantUltimate:
+		// 9x9の盤面を3x3の小盤面に分けるため、盤面と勝利条件は固定
+		if rules.BoardWidth != 9 || rules.BoardHeight != 9 || rules.WinLength != 3 {
+			return fmt.Errorf("ultimate requires a 9x9 board and winLength 3")
+		}
+		if rules.ExactLength || rules.Renju {
+			return fmt.Errorf("ultimate cannot be combined with exactLength or renju")
+		}
+	case models.VariantQuantum:
+		// 重ね合わせの印は2人対戦の3x3の盤面でのみ扱い、確定していない印の位置に依存するルールとは組み合わせられない
+		if rules.BoardWidth != 3 || rules.BoardHeight != 3 || rules.WinLength != 3 {
+			return fmt.Errorf("quantum requires a 3x3 board and winLength 3")
+		}
+		if rules.Players > 2 || rules.Teams || rules.Simultaneous {
+			return fmt.Errorf("quantum is only available for 2 players taking turns")
+		}
+		if rules.Renju || rules.Fog || rules.Obstacles != 0 || rules.PowerUps != 0 || rules.AllowUndo {
+			return fmt.Errorf("quantum cannot be combined with renju, fog, obstacles, powerUps or allowUndo")
+		}
+	default:
+		return fmt.Errorf("unknown variant %q", rules.Variant)
+	}
+	if rules.Variant != models.VariantCube && rules.BoardDepth != 0 {
+		return fmt.Errorf("boardDepth is only available for the cube variant")
+	}
+	return nil
+}
+
+// ResolveRules はルーム作成時に指定されたテーマ名を検証し、そのテーマのルールを返します。
+// テーマ名が空の場合はデフォルトテーマを使用します。
+func ResolveRules(roomTheme string) (string, models.GameRules, error) {
+	if roomTheme == "" {
+		roomTheme = DefaultThemeName
+	}
+	theme, ok := Lookup(roomTheme)
+	if !ok {
+		return "", models.GameRules{}, fmt.Errorf("%w: %s", ErrUnknownTheme, roomTheme)
+	}
+	return theme.Name, theme.Rules, nil
+}
+
+// RulesForRoom はGameRoomに保存されたルールを返します。
+// ルールが保存される前に作成されたルームの場合はRoomThemeから決定します。
+func RulesForRoom(gameRoom models.GameRoom) models.GameRules {
+	if gameRoom.Rules.BoardWidth > 0 {
+		return gameRoom.Rules
+	}
+	theme, _ := Lookup(gameRoom.RoomTheme)
+	return theme.Rules
+}
@@ -0,0 +1,368 @@
+package themes
+
+import (
+	"sync"
+
+	"xicserver/models"
+)
+
+// DefaultThemeName は未登録のRoomThemeが指定された場合に使われるテーマ名
+const DefaultThemeName = "3x3_fair"
+
+// Theme はルームのテーマ名とそのルール設定の組
+type Theme struct {
+	Name  string
+	Rules models.GameRules
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Theme)
+)
+
+// Register はテーマを登録します。同じ名前のテーマは上書きされます。
+func Register(theme Theme) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[theme.Name] = theme
+}
+
+// Lookup は名前に対応するテーマを返します。見つからない場合はデフォルトテーマとfalseを返します。
+func Lookup(name string) (Theme, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if theme, ok := registry[name]; ok {
+		return theme, true
+	}
+	return registry[DefaultThemeName], false
+}
+
+// 標準で提供するテーマの登録
+func init() {
+	Register(Theme{
+		Name: DefaultThemeName,
+		Rules: models.GameRules{
+			BoardWidth:     3,
+			BoardHeight:    3,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasFair,
+			MarkAccuracy:   0.3,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			AllowUndo:      true,
+		},
+	})
+	Register(Theme{
+		Name: "3x3_biased",
+		Rules: models.GameRules{
+			BoardWidth:     3,
+			BoardHeight:    3,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.3,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+	Register(Theme{
+		Name: "5x5_biased",
+		Rules: models.GameRules{
+			BoardWidth:     5,
+			BoardHeight:    5,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.3,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+	// 既存のテーマは制限時間なしのまま、1手30秒の制限時間を加えたテーマを別の名前で登録する
+	for _, name := range []string{DefaultThemeName, "3x3_biased", "5x5_biased"} {
+		timed, _ := Lookup(name)
+		timed.Name = name + "_timed"
+		timed.Rules.TurnTimeLimit = 30
+		Register(timed)
+	}
+	Register(Theme{
+		Name: "3x3_ranked",
+		Rules: models.GameRules{
+			BoardWidth:     3,
+			BoardHeight:    3,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasFair,
+			MarkAccuracy:   0.3,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			TimeBank:       180,
+			TimeIncrement:  3,
+			Ranked:         true,
+		},
+	})
+	// 五目並べ。盤面が広いため、中立な審判はほぼ選択どおりに石を置く
+	gomoku := models.GameRules{
+		BoardWidth:     15,
+		BoardHeight:    15,
+		WinLength:      5,
+		MatchFormat:    models.MatchBestOf,
+		Rounds:         1,
+		TargetWins:     1,
+		Bias:           models.BiasBiased,
+		MarkAccuracy:   0.9,
+		TurnTimeLimit:  60,
+		TimeoutPenalty: models.TimeoutRandomMove,
+	}
+	Register(Theme{Name: "15x15_gomoku", Rules: gomoku})
+
+	// 長連を勝ちと認めない標準的な五目並べ
+	standard := gomoku
+	standard.ExactLength = true
+	Register(Theme{Name: "15x15_gomoku_standard", Rules: standard})
+
+	// 先手に禁じ手がある連珠
+	renju := gomoku
+	renju.Renju = true
+	Register(Theme{Name: "15x15_renju", Rules: renju})
+
+	// アルティメット三目並べ。1ラウンドが長いため3本勝負の先に2勝
+	Register(Theme{
+		Name: "9x9_ultimate",
+		Rules: models.GameRules{
+			Variant:        models.VariantUltimate,
+			BoardWidth:     9,
+			BoardHeight:    9,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  45,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 4x4x4の3次元の三目並べ。層をまたぐ列も含めて76通りの列がある
+	Register(Theme{
+		Name: "4x4x4_cube",
+		Rules: models.GameRules{
+			Variant:        models.VariantCube,
+			BoardWidth:     4,
+			BoardHeight:    4,
+			BoardDepth:     4,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  45,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 重力ルールの四目並べ。賄賂を受けた審判は印を隣の列に落とす
+	Register(Theme{
+		Name: "7x6_gravity",
+		Rules: models.GameRules{
+			Variant:        models.VariantGravity,
+			BoardWidth:     7,
+			BoardHeight:    6,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// ミゼール。三目を揃えたプレイヤーの負け
+	Register(Theme{
+		Name: "3x3_misere",
+		Rules: models.GameRules{
+			BoardWidth:     3,
+			BoardHeight:    3,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.3,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			Misere:         true,
+		},
+	})
+	// OrderとChaos。6x6の盤面でちょうど5個の列を揃えればOrderの勝ち。先手と後手を交代して2ラウンド行う
+	Register(Theme{
+		Name: "6x6_order_chaos",
+		Rules: models.GameRules{
+			Variant:        models.VariantOrderChaos,
+			BoardWidth:     6,
+			BoardHeight:    6,
+			WinLength:      5,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         2,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			ExactLength:    true,
+		},
+	})
+
+	// 5x5_biasedの盤面の端をつなげたルール
+	Register(Theme{
+		Name: "5x5_biased_wrap",
+		Rules: models.GameRules{
+			Variant:        models.VariantToroidal,
+			BoardWidth:     5,
+			BoardHeight:    5,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.3,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 霧のルール。相手の印は、置こうとして失敗するか、糾弾が当たるまで見えない
+	Register(Theme{
+		Name: "5x5_fog",
+		Rules: models.GameRules{
+			BoardWidth:     5,
+			BoardHeight:    5,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			Fog:            true,
+		},
+	})
+
+	// 障害物のあるルール。ラウンドごとに印を置けないセルがランダムに配置される
+	Register(Theme{
+		Name: "6x6_obstacles",
+		Rules: models.GameRules{
+			BoardWidth:     6,
+			BoardHeight:    6,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.5,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			Obstacles:      6,
+		},
+	})
+
+	// パワーアップのあるルール。消去・入れ替え・シールドを1枚ずつ持ってマッチを始める
+	Register(Theme{
+		Name: "5x5_power_ups",
+		Rules: models.GameRules{
+			BoardWidth:     5,
+			BoardHeight:    5,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.5,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+			PowerUps:       1,
+		},
+	})
+
+	// 3人対戦（X、O、Δ）。6x6の盤面で四目を揃えたプレイヤーの勝ち
+	Register(Theme{
+		Name: "6x6_three_players",
+		Rules: models.GameRules{
+			Players:        3,
+			BoardWidth:     6,
+			BoardHeight:    6,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.5,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 2対2のチーム戦。同じチームの2人は同じ印を使い、チームが交互に手番を持つ
+	Register(Theme{
+		Name: "6x6_doubles",
+		Rules: models.GameRules{
+			Teams:          true,
+			BoardWidth:     6,
+			BoardHeight:    6,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.5,
+			TurnTimeLimit:  30,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 同時手番。両方のプレイヤーが非公開でセルを提出し、同じセルを選んだ場合は審判が決める
+	Register(Theme{
+		Name: "5x5_simultaneous",
+		Rules: models.GameRules{
+			Simultaneous:   true,
+			BoardWidth:     5,
+			BoardHeight:    5,
+			WinLength:      4,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  20,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+
+	// 量子三目並べ。もつれが循環すると相手が測定するが、賄賂を受けた審判は測定の結果を逆にすることがある
+	Register(Theme{
+		Name: "3x3_quantum",
+		Rules: models.GameRules{
+			Variant:        models.VariantQuantum,
+			BoardWidth:     3,
+			BoardHeight:    3,
+			WinLength:      3,
+			MatchFormat:    models.MatchBestOf,
+			Rounds:         3,
+			TargetWins:     2,
+			Bias:           models.BiasBiased,
+			MarkAccuracy:   0.7,
+			TurnTimeLimit:  45,
+			TimeoutPenalty: models.TimeoutRandomMove,
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"fmt"
+
+	"xicserver/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MigrateGameRooms はgame_roomsテーブルに、モデルに後から追加したカラム（rule_で始まるルール設定、
+// vs_bot、bot_level、bot_seedなど）がなければ追加します。既存のカラムの型や値は変更しません。
+// 追加前に作成されたルームのルール設定のカラムはNULLとなり、RulesForRoomがテーマのルールを使います。
+func MigrateGameRooms(db *gorm.DB, logger *zap.Logger) error {
+	statement := &gorm.Statement{DB: db}
+	if err := statement.Parse(&models.GameRoom{}); err != nil {
+		return fmt.Errorf("failed to parse the game room model: %w", err)
+	}
+
+	migrator := db.Migrator()
+	for _, field := range statement.Schema.Fields {
+		if field.DBName == "" || migrator.HasColumn(&models.GameRoom{}, field.DBName) {
+			continue
+		}
+		if err := migrator.AddColumn(&models.GameRoom{}, field.DBName); err != nil {
+			return fmt.Errorf("failed to add column %s to game_rooms: %w", field.DBName, err)
+		}
+		logger.Info("Column added to game_rooms", zap.String("column", field.DBName))
+	}
+	return nil
+}
@@ -88,12 +88,17 @@ func WebSocketConnections(ctx context.Context, w http.ResponseWriter, r *http.Re
 	randGen := bribe.CreateLocalRandGenerator()
 
 	// ゲームインスタンスの管理
-	_, err = connection.ManageGameInstance(ctx, db, logger, games, client, conn)
+	game, err := connection.ManageGameInstance(ctx, db, logger, games, client, conn)
 	if err != nil {
 		http.Error(w, "Failed to manage game instance", http.StatusInternalServerError)
 		return
 	}
 
+	// 対戦が始まった場合に備えて手番の制限時間のタイマーを設定
+	actions.ScheduleTurnTimer(game, clients, randGen, db, logger)
+	// ボットと対戦するルームでは、ボットが先手ならすぐに行動を始める
+	actions.ScheduleBots(game, clients, randGen, db, logger)
+
 	// クライアントごとにメッセージ読み取りゴルーチンを起動（）
 	go actions.HandleClient(client, clients, games, randGen, db, logger)
 
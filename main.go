@@ -62,6 +62,10 @@ func main() {
 		if err != nil {
 			logger.Fatal("PostgreSQLの初期化に失敗しました", zap.Error(err))
 		}
+		// ルームのルール設定やボットの設定のカラムを既存のテーブルに追加
+		if err := database.MigrateGameRooms(db, logger); err != nil {
+			logger.Fatal("Failed to migrate game rooms", zap.Error(err))
+		}
 		done <- true
 	}()
 
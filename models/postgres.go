@@ -22,8 +22,12 @@ type GameRoom struct {
 	FinishTime       int64
 	StartTime        int64
 	RoomTheme        string
-	ChallengersCount int          `gorm:"default:0"`             // 申請者数
-	Challengers      []Challenger `gorm:"foreignKey:GameRoomID"` // 結びつく入室申請を取得
+	Rules            GameRules    `gorm:"embedded;embeddedPrefix:rule_"` // 作成時に確定したルール設定
+	VsBot            bool         `gorm:"default:false"`                 // 作成者以外の席をボットが埋めるルームかどうか
+	BotLevel         string       `gorm:"default:'easy'"`                // ボットの強さ（"easy"、"medium"、"hard"）
+	BotSeed          int64        `gorm:"default:0"`                     // ボットの乱数のシード。0の場合はゲームの作成時に決める
+	ChallengersCount int          `gorm:"default:0"`                     // 申請者数
+	Challengers      []Challenger `gorm:"foreignKey:GameRoomID"`         // 結びつく入室申請を取得
 }
 
 // 挑戦者は別テーブルで管理（複数の挑戦者に対応）
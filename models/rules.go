@@ -0,0 +1,68 @@
+package models
+
+// マッチ形式
+const (
+	MatchBestOf  = "bestOf"  // N本勝負。過半数を取った時点で決着
+	MatchFirstTo = "firstTo" // 先にK勝したプレイヤーの勝利
+)
+
+// 審判の不正の有無
+const (
+	BiasFair   = "fair"   // 審判は賄賂を受け取らず、糾弾も受け付けない
+	BiasBiased = "biased" // 賄賂と糾弾で審判を傾けられる
+)
+
+// 手番の制限時間が切れた場合のペナルティ
+const (
+	TimeoutRandomMove   = "randomMove"   // 空いているセルにランダムに印を置く
+	TimeoutSkipTurn     = "skipTurn"     // 手番をスキップする
+	TimeoutForfeitRound = "forfeitRound" // そのラウンドを負けとする
+)
+
+// ルールのバリエーション。盤面の構造や印の置き方、勝敗の判定方法が異なる
+const (
+	VariantStandard   = ""           // 縦横の盤面でWinLength個を並べる標準ルール
+	VariantUltimate   = "ultimate"   // 3x3の小盤面を3x3に並べたアルティメット三目並べ
+	VariantCube       = "cube"       // BoardDepth層の盤面を重ねた3次元の三目並べ
+	VariantGravity    = "gravity"    // 列を選ぶと印が一番下の空いたセルに落ちる重力ルール
+	VariantOrderChaos = "orderChaos" // 毎手XかOを選んで置き、Orderは列を揃え、Chaosは阻止を目指す
+	VariantToroidal   = "toroidal"   // 盤面の上下左右の端がつながっており、列が端をまたいで続く
+	VariantQuantum    = "quantum"    // 毎手2つのセルに重ね合わせの印を置き、もつれが循環すると測定で確定する量子三目並べ
+)
+
+// パワーアップの種類
+const (
+	PowerUpErase  = "erase"  // 相手の印を1つ消す
+	PowerUpSwap   = "swap"   // 自分の印と相手の印を1つずつ入れ替える
+	PowerUpShield = "shield" // 審判にずらされず、消去や入れ替えもされない印を置く
+)
+
+// GameRules はルームのテーマまたは作成時のカスタム設定から決まるゲームのルール設定
+type GameRules struct {
+	Variant        string  `json:"variant"`        // ルールのバリエーション。空文字列は標準ルール
+	Players        int     `json:"players"`        // 対戦する人数。0は2人
+	Teams          bool    `json:"teams"`          // 2人ずつのチームで同じ印を使う2対2のチーム戦かどうか
+	Simultaneous   bool    `json:"simultaneous"`   // 全員が同時にセルを提出する同時手番かどうか
+	BoardWidth     int     `json:"boardWidth"`     // 盤面の列数
+	BoardHeight    int     `json:"boardHeight"`    // 盤面の行数
+	BoardDepth     int     `json:"boardDepth"`     // 3次元の盤面の層の数。縦横の盤面では0
+	WinLength      int     `json:"winLength"`      // 勝利に必要な連続数
+	MatchFormat    string  `json:"matchFormat"`    // MatchBestOf または MatchFirstTo
+	Rounds         int     `json:"rounds"`         // 最大ラウンド数（bestOfのN、firstToでは打ち切りまでのラウンド数）
+	TargetWins     int     `json:"targetWins"`     // firstToで勝利に必要なラウンド勝利数。bestOfではラウンド数の過半数
+	Bias           string  `json:"bias"`           // BiasFair または BiasBiased、不正の有無
+	MarkAccuracy   float64 `json:"markAccuracy"`   // 審判が中立な場合に、選択したセルにそのまま印が置かれる確率
+	BribeLimit     int     `json:"bribeLimit"`     // 1ラウンドで1人が贈れる賄賂の上限。0は無制限
+	TurnTimeLimit  int     `json:"turnTimeLimit"`  // 1手の制限時間（秒）。0は無制限
+	TimeoutPenalty string  `json:"timeoutPenalty"` // 制限時間切れのペナルティ
+	TimeBank       int     `json:"timeBank"`       // マッチ全体の1人あたりの持ち時間（秒）。0は持ち時間なし
+	TimeIncrement  int     `json:"timeIncrement"`  // 1手ごとに持ち時間に加算される時間（秒）
+	Ranked         bool    `json:"ranked"`         // ランクマッチ用のテーマかどうか
+	AllowUndo      bool    `json:"allowUndo"`      // 対戦相手の同意による待ったを認めるかどうか
+	ExactLength    bool    `json:"exactLength"`    // ちょうどWinLength個の列だけを勝利とする（長連は勝利にならない）
+	Renju          bool    `json:"renju"`          // ラウンドの先手に連珠の禁じ手（長連・四四・三三）を適用する
+	Misere         bool    `json:"misere"`         // 列を完成させたプレイヤーの負けとする
+	Fog            bool    `json:"fog"`            // ラウンド中は公開されていない相手の印を隠す
+	Obstacles      int     `json:"obstacles"`      // ラウンド開始時にランダムに配置する、印を置けないセルの数
+	PowerUps       int     `json:"powerUps"`       // マッチの開始時に配られる各パワーアップの枚数。0はパワーアップなし
+}
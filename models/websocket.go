@@ -1,6 +1,9 @@
 package models
 
 import (
+	"sync"
+	"time"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -17,24 +20,122 @@ type Client struct {
 type Game struct {
 	ID                  uint
 	Board               [][]string
-	Players             [2]*Player
-	PlayersOnlineStatus map[uint]bool // キー: Player ID, 値: オンライン状態
-	CurrentTurn         uint          // "player1" または "player2"
-	Status              string        // "waiting", "in progress", "finished", "round1", "round2" など
-	BribeCounts         [2]int        // プレイヤー1とプレイヤー2の賄賂回数
-	Bias                string        // "fair" または "biased"、不正の有無
-	BiasDegree          int           // 不正度合い。賄賂の影響による変動値
-	RefereeStatus       string        // 審判の状態（例: "normal", "biased", "sad", "angry"）
-	RefereeCount        uint          // 0以上の場合はRefereeStatusが異常値に固定される
-	RoomTheme           string        // ゲームモード
-	Winners             []uint        // 各ラウンドの勝者のID。3要素までのスライス。引き分けの場合は、0やnil
-	RetryRequests       map[uint]bool // キー: Player ID, 値: 再戦リクエストの有無
+	Players             []*Player               // ルールの人数分の枠。未参加の枠はnil
+	PlayersOnlineStatus map[uint]bool           // キー: Player ID, 値: オンライン状態
+	CurrentTurn         uint                    // 現在の手番のプレイヤーID
+	FirstMover          uint                    // 現在のラウンドの先手のプレイヤーID。ラウンドの開始時に記録する
+	TurnStartedAt       time.Time               // 現在の手番が始まった時刻。持ち時間の計算に使用
+	TurnDeadline        time.Time               // 現在の手番の期限。制限時間がない場合はゼロ値
+	TurnTimer           *time.Timer             // 期限切れを検知するためのタイマー（サーバー側で管理）
+	Mu                  *sync.Mutex             // このゲームの状態を読み書きする処理を直列化するためのロック（サーバー側で管理）
+	Status              string                  // "in_progress", "round_finished", "finished"
+	Round               int                     // 現在のラウンド番号（1から始まる）
+	BribeCounts         []int                   // Playersと同じ順の各プレイヤーの賄賂回数
+	BiasDegree          int                     // 不正度合い。0は中立で、正の値はFavoredPlayerに有利
+	FavoredPlayer       uint                    // 審判が有利に扱うプレイヤーのID。BiasDegreeが0の場合は0
+	RefereeStatus       string                  // 審判の状態（例: "normal", "biased", "sad", "angry"）
+	RefereeCount        uint                    // 0以上の場合はRefereeStatusが異常値に固定される
+	RoomTheme           string                  // ゲームモード
+	Rules               GameRules               // テーマから決まるルール設定
+	Winners             []uint                  // 各ラウンドの勝者のID。引き分けの場合は0
+	WinningLines        []*WinLine              // 各ラウンドの勝利を決めた列。Winnersと同じ順で、列が揃わなかったラウンドはnil
+	MatchWinner         uint                    // マッチ全体の勝者のID。未決着または引き分けの場合は0
+	FinishReason        string                  // ラウンドの勝敗以外でマッチが終了した理由（"flagged"など）
+	Clocks              map[uint]time.Duration  // キー: Player ID, 値: 残りの持ち時間（手番中の経過時間は含まない）
+	RetryRequests       map[uint]bool           // キー: Player ID, 値: 再戦リクエストの有無
+	DrawOffer           *DrawOffer              // 保留中の引き分けの提案。なければnil
+	UndoRequest         *UndoRequest            // 保留中の待ったの要求。なければnil
+	History             []MoveSnapshot          // 現在のラウンドの手の履歴
+	LastMove            *Move                   // 現在のラウンドで最後に置かれた印。差分の描画に使用
+	Ultimate            *UltimateState          // アルティメット三目並べの小盤面の状態。他のバリエーションではnil
+	Quantum             *QuantumState           // 量子三目並べの重ね合わせの状態。他のバリエーションではnil
+	Revealed            [][]bool                // 霧のルールで両方のプレイヤーに公開されたセル。Boardと同じ形で、未公開のみの場合はnil
+	Hands               map[uint]map[string]int // キー: Player ID, 値: パワーアップの種類ごとの残り枚数
+	Shielded            [][2]int                // 現在のラウンドでシールドで置かれた印のセル
+	PendingMoves        map[uint][2]int         // 同時手番で提出済みのセル。キー: Player ID。全員の提出が揃うまで公開しない
+	LastMoves           []Move                  // 同時手番で最後にまとめて置かれた印
+	RoundResigned       []uint                  // 3人以上の対戦で現在のラウンドを投了したプレイヤーのID。手番が回らなくなる
+}
+
+// DrawOffer はプレイヤーからの引き分けの提案
+type DrawOffer struct {
+	From       uint      `json:"from"`                 // 提案したプレイヤーのID
+	Scope      string    `json:"scope"`                // "round" または "match"
+	ExpiresAt  time.Time `json:"expiresAt"`            // 提案の有効期限
+	AcceptedBy []uint    `json:"acceptedBy,omitempty"` // 3人以上の対戦で受諾済みのプレイヤーのID
+}
+
+// UndoRequest はプレイヤーからの待った（直前の手の取り消し）の要求
+type UndoRequest struct {
+	From uint `json:"from"` // 要求したプレイヤーのID
+}
+
+// MoveSnapshot は印が置かれる直前のゲームの状態。待ったで巻き戻すために使用
+type MoveSnapshot struct {
+	PlayerID      uint       // 手を指したプレイヤーのID
+	Board         [][]string // 印が置かれる前の盤面
+	CurrentTurn   uint
+	RefereeCount  uint
+	BiasDegree    int
+	FavoredPlayer uint
+	RefereeStatus string
+	LastMove      *Move
+	Ultimate      *UltimateState
+	Hands         map[uint]map[string]int
+	Shielded      [][2]int
+	Revealed      [][]bool               // 霧のルールで公開されていたセル
+	Clocks        map[uint]time.Duration // 手を指す前の持ち時間。待ったで手に使った時間を戻すために使用
+}
+
+// UltimateState はアルティメット三目並べの小盤面の状態。
+// 盤面自体は9x9のBoardで表し、Board[x][y]は小盤面[x/3][y/3]のセル[x%3][y%3]に対応する
+type UltimateState struct {
+	SubBoards [][]string `json:"subBoards"` // 各小盤面の勝者のシンボル。未決着は空文字列、引き分けは"-"
+	Target    *[2]int    `json:"target"`    // 次に印を置く小盤面。nilの場合は未決着の任意の小盤面
+}
+
+// QuantumState は量子三目並べの重ね合わせの状態。
+// 測定で確定した印はBoardに置き、まだ確定していない手は2つのセルを結ぶもつれとしてMovesに残す
+type QuantumState struct {
+	Moves      []QuantumMove `json:"moves"`      // 確定していない手。セルを頂点、手を辺とするもつれのグラフになる
+	Subscripts [][]int       `json:"subscripts"` // 確定した印の手の番号。Boardと同じ形で、未確定のセルは0
+	MoveCount  int           `json:"moveCount"`  // ラウンド内でこれまでに打たれた手の数
+	Collapse   *QuantumMove  `json:"collapse"`   // もつれの循環を作った手。手番のプレイヤーが測定するまで次の手は打てない
+}
+
+// QuantumMove は2つのセルに重ね合わせで置かれた印
+type QuantumMove struct {
+	PlayerID uint      `json:"playerId"`
+	Symbol   string    `json:"symbol"`
+	Number   int       `json:"number"` // 手の番号（1から始まる）
+	Cells    [2][2]int `json:"cells"`
+}
+
+// Move は盤面に置かれた印。審判によって選択とは別のセルに置かれた場合は、実際に置かれたセル
+type Move struct {
+	PlayerID uint   `json:"playerId"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Z        int    `json:"z,omitempty"` // 3次元の盤面の層
+	Symbol   string `json:"symbol"`
+}
+
+// WinLine はラウンドの勝利を決めた列
+type WinLine struct {
+	Cells      [][2]int `json:"cells"`               // 列を構成するセルの座標（[x, y]）
+	Direction  string   `json:"direction"`           // "row"、"column"、"diagonal"、"antiDiagonal"
+	MoveNumber int      `json:"moveNumber"`          // 列を完成させた手がラウンドの何手目か
+	Layers     []int    `json:"layers,omitempty"`    // 3次元の盤面で、Cellsの各セルが属する層
+	SubBoards  [][2]int `json:"subBoards,omitempty"` // アルティメット三目並べで、列を構成する小盤面の座標
 }
 
 // PlayerはUserに紐づく
 type Player struct {
 	ID       uint
-	Symbol   string // "X" or "O"
+	Symbol   string // "X"、"O"、3人目は"Δ"
 	NickName string
-	Conn     *websocket.Conn
+	Conn     *websocket.Conn // ボットの場合はnil
+	Bot      bool            // サーバー側のボットかどうか
+	BotLevel string          // ボットの強さ。ボットでない場合は空文字列
+	BotSeed  int64           // ボットの判断に使う乱数のシード。同じシードなら同じ局面で同じ判断をする
 }
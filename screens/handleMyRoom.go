@@ -3,6 +3,8 @@ package screens
 import (
 	"net/http"
 
+	"xicserver/bribe/engine"
+	"xicserver/bribe/themes"
 	"xicserver/middlewares"
 	"xicserver/models"
 
@@ -109,6 +111,24 @@ func ReplyHandler(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status value"})
 		return
 	}
+	// ルールの人数から自分を除いた数の申請者を承認済みであれば、それ以上は承認できない
+	if replyRequest.Status == "accepted" {
+		// ボットと対戦するルームの席は全てボットが埋める
+		if gameRoom.VsBot {
+			c.JSON(http.StatusConflict, gin.H{"error": "The room is played against a bot"})
+			return
+		}
+		var accepted int64
+		if err := db.Model(&models.Challenger{}).Where("game_room_id = ? AND status = ?", gameRoom.ID, "accepted").Count(&accepted).Error; err != nil {
+			logger.Error("Failed to count accepted challengers", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count accepted challengers"})
+			return
+		}
+		if int(accepted) >= engine.PlayerCount(themes.RulesForRoom(gameRoom))-1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The room is already full"})
+			return
+		}
+	}
 	if err := db.Model(&challenger).Update("status", replyRequest.Status).Error; err != nil {
 		logger.Error("Failed to update challenger status", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"xicserver/bribe/themes"
 	"xicserver/middlewares"
 	"xicserver/models"
 
@@ -34,6 +35,7 @@ func GetRoomInfo(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
 	c.JSON(http.StatusOK, gin.H{
 		"roomCreator": gameRoom.RoomCreator,
 		"roomTheme":   gameRoom.RoomTheme,
+		"rules":       themes.RulesForRoom(gameRoom),
 		"gameState":   gameRoom.GameState,
 		"createdAt":   gameRoom.CreatedAt,
 	})
@@ -3,9 +3,12 @@ package screens
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"net/http"
 	"strings"
 
+	"xicserver/bribe/bot"
+	"xicserver/bribe/themes"
 	"xicserver/middlewares"
 	"xicserver/models"
 
@@ -16,9 +19,140 @@ import (
 )
 
 type RoomCreateRequest struct {
-	SubscriptionStatus string `json:"subscriptionStatus,omitempty"` // 課金ステータス
-	Nickname           string `json:"nickname"`                     // ニックネーム
-	RoomTheme          string `json:"roomTheme"`                    // ルームのテーマ
+	SubscriptionStatus string        `json:"subscriptionStatus,omitempty"` // 課金ステータス
+	Nickname           string        `json:"nickname"`                     // ニックネーム
+	RoomTheme          string        `json:"roomTheme"`                    // ルームのテーマ
+	Rules              *RulesRequest `json:"rules,omitempty"`              // テーマのルールを上書きするカスタムルール
+	VsBot              bool          `json:"vsBot,omitempty"`              // 作成者以外の席をボットが埋めるかどうか
+	BotLevel           string        `json:"botLevel,omitempty"`           // ボットの強さ。省略した場合は"easy"
+	BotSeed            int64         `json:"botSeed,omitempty"`            // ボットの乱数のシード。ボットとの対戦を再現する場合に指定する
+}
+
+// RulesRequest はルーム作成時に指定するカスタムルールです。省略した項目はテーマの設定を引き継ぎます。
+type RulesRequest struct {
+	Players        *int     `json:"players,omitempty"`        // 対戦する人数
+	Teams          *bool    `json:"teams,omitempty"`          // 2対2のチーム戦かどうか
+	Simultaneous   *bool    `json:"simultaneous,omitempty"`   // 全員が同時にセルを提出する同時手番かどうか
+	BoardWidth     *int     `json:"boardWidth,omitempty"`     // 盤面の列数
+	BoardHeight    *int     `json:"boardHeight,omitempty"`    // 盤面の行数
+	BoardDepth     *int     `json:"boardDepth,omitempty"`     // 3次元の盤面の層の数
+	WinLength      *int     `json:"winLength,omitempty"`      // 勝利に必要な連続数
+	MatchFormat    *string  `json:"matchFormat,omitempty"`    // "bestOf" または "firstTo"
+	Rounds         *int     `json:"rounds,omitempty"`         // 最大ラウンド数
+	TargetWins     *int     `json:"targetWins,omitempty"`     // firstToで勝利に必要なラウンド勝利数。bestOfで省略した場合はラウンド数の過半数
+	Biased         *bool    `json:"biased,omitempty"`         // 審判の不正の有無
+	MarkAccuracy   *float64 `json:"markAccuracy,omitempty"`   // 審判が中立な場合に選択したセルに印が置かれる確率
+	BribeLimit     *int     `json:"bribeLimit,omitempty"`     // 1ラウンドの賄賂の上限。0は無制限
+	TurnTimeLimit  *int     `json:"turnTimeLimit,omitempty"`  // 1手の制限時間（秒）。0は無制限
+	TimeoutPenalty *string  `json:"timeoutPenalty,omitempty"` // "randomMove"、"skipTurn"、"forfeitRound"
+	TimeBank       *int     `json:"timeBank,omitempty"`       // 1人あたりの持ち時間（秒）。0は持ち時間なし
+	TimeIncrement  *int     `json:"timeIncrement,omitempty"`  // 1手ごとの加算時間（秒）
+	AllowUndo      *bool    `json:"allowUndo,omitempty"`      // 対戦相手の同意による待ったを認めるかどうか
+	ExactLength    *bool    `json:"exactLength,omitempty"`    // ちょうどwinLength個の列だけを勝利とするかどうか
+	Renju          *bool    `json:"renju,omitempty"`          // 先手に連珠の禁じ手を適用するかどうか
+	Misere         *bool    `json:"misere,omitempty"`         // 列を完成させたプレイヤーの負けとするかどうか
+	Fog            *bool    `json:"fog,omitempty"`            // 公開されていない相手の印を隠すかどうか
+	Obstacles      *int     `json:"obstacles,omitempty"`      // ラウンド開始時に配置する障害物の数
+	PowerUps       *int     `json:"powerUps,omitempty"`       // マッチの開始時に配られる各パワーアップの枚数
+}
+
+// 不正のあるルームで待ったを認めるよう指定された場合のエラー
+var errUndoInBiasedRoom = errors.New("allowUndo cannot be enabled in a biased room")
+
+// テーマのルールにカスタムルールで指定された項目を上書きするヘルパー関数。
+// 同時に指定できない項目が指定された場合はエラーを返す
+func (r *RulesRequest) applyTo(rules models.GameRules) (models.GameRules, error) {
+	if r == nil {
+		return rules, nil
+	}
+	if r.Players != nil {
+		rules.Players = *r.Players
+	}
+	if r.Teams != nil {
+		rules.Teams = *r.Teams
+	}
+	if r.Simultaneous != nil {
+		rules.Simultaneous = *r.Simultaneous
+	}
+	if r.BoardWidth != nil {
+		rules.BoardWidth = *r.BoardWidth
+	}
+	if r.BoardHeight != nil {
+		rules.BoardHeight = *r.BoardHeight
+	}
+	if r.BoardDepth != nil {
+		rules.BoardDepth = *r.BoardDepth
+	}
+	if r.WinLength != nil {
+		rules.WinLength = *r.WinLength
+	}
+	if r.MatchFormat != nil {
+		rules.MatchFormat = *r.MatchFormat
+	}
+	if r.Rounds != nil {
+		rules.Rounds = *r.Rounds
+		// bestOfで勝利数が指定されていない場合は、テーマの値ではなくラウンド数の過半数とする
+		if r.TargetWins == nil && rules.MatchFormat == models.MatchBestOf {
+			rules.TargetWins = rules.Rounds/2 + 1
+		}
+	}
+	if r.TargetWins != nil {
+		rules.TargetWins = *r.TargetWins
+	}
+	if r.Biased != nil {
+		rules.Bias = models.BiasFair
+		if *r.Biased {
+			rules.Bias = models.BiasBiased
+			// 不正のあるルームでは待ったを無効にする
+			rules.AllowUndo = false
+		}
+	}
+	if r.MarkAccuracy != nil {
+		rules.MarkAccuracy = *r.MarkAccuracy
+	}
+	if r.BribeLimit != nil {
+		rules.BribeLimit = *r.BribeLimit
+	}
+	if r.TurnTimeLimit != nil {
+		rules.TurnTimeLimit = *r.TurnTimeLimit
+	}
+	if r.TimeoutPenalty != nil {
+		rules.TimeoutPenalty = *r.TimeoutPenalty
+	}
+	if r.TimeBank != nil {
+		rules.TimeBank = *r.TimeBank
+	}
+	if r.TimeIncrement != nil {
+		rules.TimeIncrement = *r.TimeIncrement
+	}
+	if r.AllowUndo != nil {
+		// 不正のあるルームでは待ったを認めない。指定を黙って無視せずに拒否する
+		if *r.AllowUndo && rules.Bias == models.BiasBiased {
+			return rules, errUndoInBiasedRoom
+		}
+		rules.AllowUndo = *r.AllowUndo
+	}
+	if r.ExactLength != nil {
+		rules.ExactLength = *r.ExactLength
+	}
+	if r.Renju != nil {
+		rules.Renju = *r.Renju
+	}
+	if r.Misere != nil {
+		rules.Misere = *r.Misere
+	}
+	if r.Fog != nil {
+		rules.Fog = *r.Fog
+	}
+	if r.Obstacles != nil {
+		rules.Obstacles = *r.Obstacles
+	}
+	if r.PowerUps != nil {
+		rules.PowerUps = *r.PowerUps
+	}
+	// ルールを変更したルームはランクマッチとして扱わない
+	rules.Ranked = false
+	return rules, nil
 }
 
 func NewGame(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
@@ -32,6 +166,48 @@ func NewGame(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
 		return
 	}
 
+	// テーマとカスタムルールを検証し、ルームに保存するルールを決定
+	roomTheme, rules, err := themes.ResolveRules(request.RoomTheme)
+	if err != nil {
+		logger.Info("Room create request with unknown theme", zap.String("roomTheme", request.RoomTheme))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "unknown_room_theme",
+			"message": err.Error(),
+		})
+		return
+	}
+	rules, err = request.Rules.applyTo(rules)
+	if err != nil {
+		logger.Info("Room create request with conflicting rules", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "invalid_rules",
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.VsBot {
+		rules.Ranked = false // ボットとの対戦はランクマッチとして扱わない
+		if request.BotLevel == "" {
+			request.BotLevel = bot.LevelEasy
+		}
+		if !bot.IsValidLevel(request.BotLevel) {
+			logger.Info("Room create request with unknown bot level", zap.String("botLevel", request.BotLevel))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "invalid_bot_level",
+				"message": "botLevel must be one of easy, medium or hard",
+			})
+			return
+		}
+	}
+	if err := themes.ValidateRules(rules); err != nil {
+		logger.Info("Room create request with invalid rules", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "invalid_rules",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// トークンをヘッダーから取得
 	tokenString := c.GetHeader("Authorization")
 	// Bearerトークンのプレフィックスを確認し、存在する場合は削除
@@ -44,7 +220,7 @@ func NewGame(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
 	var tokenValid bool = false // トークンの有効性を判定するフラグ
 
 	// TokenAuthentication関数でJWTの有効性を確認、無効であれば更新されたトークンを送付する
-	userID, newToken, tokenValid, err := middlewares.TokenAuthentication(c, db, logger, request.SubscriptionStatus)
+	userID, newToken, tokenValid, err = middlewares.TokenAuthentication(c, db, logger, request.SubscriptionStatus)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token processing failed", "newToken": newToken})
 		return
@@ -98,7 +274,11 @@ func NewGame(c *gin.Context, db *gorm.DB, logger *zap.Logger) {
 			RoomCreator: request.Nickname,
 			GameState:   "created",
 			UniqueToken: uniqueToken,
-			RoomTheme:   request.RoomTheme,
+			RoomTheme:   roomTheme,
+			Rules:       rules,
+			VsBot:       request.VsBot,
+			BotLevel:    request.BotLevel,
+			BotSeed:     request.BotSeed,
 		}
 		if err := db.Create(&newGameRoom).Error; err != nil {
 			logger.Error("Failed to create a new game room", zap.Error(err))